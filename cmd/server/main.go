@@ -1,34 +1,94 @@
 package main
 
 import (
+	"context"
 	"happyAssistant/internal/config"
 	"happyAssistant/internal/controller"
 	"happyAssistant/internal/initialize"
 	"happyAssistant/internal/logger"
 	"happyAssistant/pkg/wshub"
-	"time"
+	"os/signal"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func StartWebsocketServer() {
-	hub := wshub.GetInstance()
+// buildRateLimit 将配置转换为客户端限流选项
+func buildRateLimit(cfg config.RateLimitConfig) wshub.RateLimitConfig {
+	rateLimit := wshub.RateLimitConfig{
+		Limit:     wshub.RateLimit{Rate: cfg.Rate, Burst: cfg.Burst},
+		Classify:  controller.ClassifyMessage,
+		KeyLimits: make(map[string]wshub.RateLimit, len(cfg.Protocols)),
+	}
+	switch cfg.Action {
+	case "reply":
+		rateLimit.Action = wshub.RateLimitReply
+	case "disconnect":
+		rateLimit.Action = wshub.RateLimitDisconnect
+	default:
+		rateLimit.Action = wshub.RateLimitDrop
+	}
+	for protocolType, rule := range cfg.Protocols {
+		rateLimit.KeyLimits[protocolType] = wshub.RateLimit{Rate: rule.Rate, Burst: rule.Burst}
+	}
+	return rateLimit
+}
+
+func StartWebsocketServer() (*wshub.WebSocketHub, *controller.ProtocolController) {
+	serverCfg := config.Cfg.Server
+	hub, err := wshub.NewHub(
+		wshub.WithTLS(serverCfg.CertFile, serverCfg.KeyFile),
+		wshub.WithSubprotocols(controller.Subprotocols...),
+		wshub.WithHTTPTimeouts(serverCfg.HTTP.ReadHeaderTimeout, serverCfg.HTTP.ReadTimeout,
+			serverCfg.HTTP.WriteTimeout, serverCfg.HTTP.IdleTimeout),
+		wshub.WithSessionResume(serverCfg.Resume.Grace, serverCfg.Resume.BufferSize),
+		wshub.WithMaxConnections(serverCfg.Admission.MaxConnections),
+		wshub.WithMaxConnectionsPerIP(serverCfg.Admission.MaxConnectionsPerIP),
+		wshub.WithTrustedProxies(serverCfg.Admission.TrustedProxies...),
+	)
+	if err != nil {
+		log.Fatalln("Websocket hub init error:", err)
+	}
 	hub.SetClientOptions(
-		wshub.WithReadDeadline(45*time.Second),
-		wshub.WithSupportPing(20*time.Second),
+		wshub.WithReadDeadline(serverCfg.Heartbeat.ReadDeadline()),
+		wshub.WithSupportPing(serverCfg.Heartbeat.PingPeriod),
+		wshub.WithRateLimit(buildRateLimit(serverCfg.RateLimit)),
+		wshub.WithIdleTimeout(serverCfg.Admission.IdleTimeout),
+		// 只有心跳的连接仍按空闲超时断开
+		wshub.WithActivityFilter(controller.IsActivity),
 	)
 
+	if dispatchCfg := serverCfg.Dispatch; dispatchCfg.Workers > 0 {
+		hub.SetDispatcher(wshub.NewDispatcher(dispatchCfg.Workers,
+			wshub.WithQueueLength(dispatchCfg.QueueLength),
+			wshub.WithMaxPendingPerClient(dispatchCfg.MaxPendingPerClient),
+			wshub.WithOrdered(dispatchCfg.Ordered),
+			wshub.WithParallelClassifier(controller.IsParallelSafe),
+		))
+	}
+
 	// 创建协议控制器
-	protocolController := controller.NewProtocolController()
+	protocolController := controller.NewProtocolController(hub)
+
+	// 握手阶段校验会话令牌，重连的客户端无需重新登录
+	hub.SetAuthenticator(protocolController.Authenticate)
 
 	hub.OnOpen = func(client wshub.IClient) {
 		log.Info("Client connected")
+		protocolController.HandleOpen(client)
 	}
 
-	hub.OnClose = func(client wshub.IClient) {
-		log.Info("Client disconnected")
+	hub.OnClose = func(client wshub.IClient, cause wshub.CloseCause) {
+		log.Infof("Client disconnected, user: %s, cause: %s",
+			client.GetContextString(controller.ContextKeyUserID), cause)
+		protocolController.HandleClose(client)
 	}
 
+	// 收到消息时先登记请求，排队中的请求也可以被 CANCEL_REQ 取消
+	hub.OnReceive = protocolController.HandleReceive
+	// 分发器停止后收到的请求不会被处理，移除登记并回复错误
+	hub.OnDispatchError = protocolController.HandleDispatchError
+
 	hub.OnMessage = func(client wshub.IClient, msg []byte) {
 		// 将消息路由到协议控制器处理
 		protocolController.HandleMessage(client, msg)
@@ -38,16 +98,43 @@ func StartWebsocketServer() {
 		log.Errorf("WebSocket error: %v", err)
 	}
 
-	err := hub.Start(config.Cfg.Server.Route, config.Cfg.Server.Port)
+	hub.OnRateLimited = func(client wshub.IClient, msg []byte) {
+		protocolController.HandleRateLimited(client, msg)
+	}
 
-	if err != nil {
-		log.Fatalln("Websocket server start error:", err)
+	hub.OnDrop = func(client wshub.IClient, msg *wshub.Message) {
+		log.Warnf("WebSocket message dropped, user: %s, total dropped: %d",
+			client.GetContextString(controller.ContextKeyUserID), client.GetBaseClient().DroppedCount())
 	}
+
+	go func() {
+		if err := hub.Start(serverCfg.Route, serverCfg.Port); err != nil {
+			log.Fatalln("Websocket server start error:", err)
+		}
+	}()
+	return hub, protocolController
 }
 
 func main() {
 	config.LoadConfig("configs/config_debug.yaml")
 	logger.InitLogger(config.Cfg.Log)
 	initialize.InitMongoDBClient(config.Cfg.MongoDB)
-	StartWebsocketServer()
+	hub, protocolController := StartWebsocketServer()
+
+	// 等待退出信号
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := hub.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Websocket server shutdown error: %v", err)
+	}
+	protocolController.Close()
+	if err := initialize.CloseMongoDBClient(shutdownCtx); err != nil {
+		log.Errorf("Mongo disconnect error: %v", err)
+	}
+	log.Info("Server exited")
 }
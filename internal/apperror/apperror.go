@@ -0,0 +1,101 @@
+// Package apperror 应用错误
+// 将服务层错误转换为带业务错误码的应用错误：客户端只收到错误码、安全的提示消息和可选的详情，
+// 内部原因（数据库错误等）保存在 Cause 中，只记录在服务端日志里
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"happyAssistant/internal/model"
+	"maps"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Error 应用错误
+type Error struct {
+	Code    model.ErrorCode
+	Message string            // 返回给客户端的提示消息，不包含内部细节
+	Details map[string]string // 返回给客户端的错误详情
+	Cause   error             // 内部原因，只记录日志
+}
+
+// Error 返回包含内部原因的完整描述，用于日志，不应直接返回给客户端
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+}
+
+// Unwrap 返回内部原因
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithDetail 返回附加了详情的副本，原错误不变，可以安全地用于包级错误变量
+func (e *Error) WithDetail(key, value string) *Error {
+	clone := *e
+	clone.Details = make(map[string]string, len(e.Details)+1)
+	maps.Copy(clone.Details, e.Details)
+	clone.Details[key] = value
+	return &clone
+}
+
+// New 创建应用错误
+func New(code model.ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap 创建带内部原因的应用错误
+func Wrap(cause error, code model.ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message, Cause: cause}
+}
+
+// Mapping 服务层错误到应用错误的映射
+type Mapping struct {
+	Target  error // 通过 errors.Is 匹配的服务层错误
+	Code    model.ErrorCode
+	Message string
+}
+
+// From 将任意错误转换为应用错误
+// 已是应用错误（包括被包装的）时直接返回；否则依次匹配 mappings 和通用错误（记录不存在、超时、取消），
+// 都不匹配时视为内部错误，客户端只会看到通用提示
+func From(err error, mappings ...Mapping) *Error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	for _, m := range mappings {
+		if errors.Is(err, m.Target) {
+			return Wrap(err, m.Code, m.Message)
+		}
+	}
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return Wrap(err, model.ErrorCode_NOT_FOUND, "Resource not found")
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err), mongo.IsNetworkError(err):
+		return Wrap(err, model.ErrorCode_UNAVAILABLE, "Service temporarily unavailable, please retry later")
+	case errors.Is(err, context.Canceled):
+		return Wrap(err, model.ErrorCode_CANCELED, "Request canceled")
+	default:
+		return Wrap(err, model.ErrorCode_INTERNAL, "Internal server error")
+	}
+}
+
+// CodeOf 获取错误对应的业务错误码，nil 返回 NO_ERROR，未转换的错误返回 INTERNAL
+func CodeOf(err error) model.ErrorCode {
+	if err == nil {
+		return model.ErrorCode_NO_ERROR
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return model.ErrorCode_INTERNAL
+}
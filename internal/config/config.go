@@ -10,8 +10,80 @@ import (
 
 // ServerConfig Server配置
 type ServerConfig struct {
-	Port  int    `yaml:"port"`
-	Route string `yaml:"route"`
+	Port            int             `yaml:"port"`
+	Route           string          `yaml:"route"`
+	ShutdownTimeout time.Duration   `yaml:"shutdownTimeout"`
+	CertFile        string          `yaml:"certFile"` // TLS 证书文件，为空时使用 ws
+	KeyFile         string          `yaml:"keyFile"`  // TLS 私钥文件
+	RateLimit       RateLimitConfig `yaml:"rateLimit"`
+	Dispatch        DispatchConfig  `yaml:"dispatch"`
+	Resume          ResumeConfig    `yaml:"resume"`
+	Admission       AdmissionConfig `yaml:"admission"`
+	Heartbeat       HeartbeatConfig `yaml:"heartbeat"`
+	HTTP            HTTPConfig      `yaml:"http"`
+	Request         RequestConfig   `yaml:"request"`
+}
+
+// HTTPConfig 握手阶段的 HTTP 服务超时配置，<= 0 表示不限制
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+}
+
+// RequestConfig 请求处理超时配置，超时后请求的上下文被取消，< 0 表示不限制
+type RequestConfig struct {
+	Timeout   time.Duration            `yaml:"timeout"`   // 默认超时，未配置时为 30s
+	Protocols map[string]time.Duration `yaml:"protocols"` // 按协议类型的独立超时，key 为协议类型名称
+}
+
+// RateLimitRule 令牌桶限流参数
+type RateLimitRule struct {
+	Rate  float64 `yaml:"rate"`  // 每秒允许的消息数，<= 0 表示不限制
+	Burst int     `yaml:"burst"` // 允许的突发消息数
+}
+
+// RateLimitConfig 客户端入站消息限流配置
+type RateLimitConfig struct {
+	RateLimitRule `yaml:",inline"`
+	Action        string                   `yaml:"action"`    // 触发限流后的处理方式: drop, reply, disconnect
+	Protocols     map[string]RateLimitRule `yaml:"protocols"` // 按协议类型的独立限流，key 为协议类型名称
+}
+
+// DispatchConfig 消息分发配置
+type DispatchConfig struct {
+	Workers             int  `yaml:"workers"`             // 工作协程数量，即同时处理中的消息上限，<= 0 时在读协程中同步处理
+	QueueLength         int  `yaml:"queueLength"`         // 全局待处理队列长度
+	MaxPendingPerClient int  `yaml:"maxPendingPerClient"` // 单个客户端最多排队的消息数
+	Ordered             bool `yaml:"ordered"`             // 是否保证同一客户端的消息按顺序处理
+}
+
+// ResumeConfig 断线重连会话恢复配置
+type ResumeConfig struct {
+	Grace      time.Duration `yaml:"grace"`      // 断开后保留会话的时长，<= 0 时关闭会话恢复
+	BufferSize int           `yaml:"bufferSize"` // 每个会话保留的最近下行消息数
+}
+
+// AdmissionConfig 连接准入配置
+type AdmissionConfig struct {
+	MaxConnections      int           `yaml:"maxConnections"`      // 最大连接数，<= 0 表示不限制
+	MaxConnectionsPerIP int           `yaml:"maxConnectionsPerIP"` // 单个客户端 IP 的最大连接数，<= 0 表示不限制
+	TrustedProxies      []string      `yaml:"trustedProxies"`      // 可信代理的 IP 或 CIDR，用于获取客户端真实 IP
+	IdleTimeout         time.Duration `yaml:"idleTimeout"`         // 超过该时间未收到业务消息则断开，<= 0 表示不限制
+}
+
+// HeartbeatConfig 连接保活配置
+// 小程序的 socket 无法可靠地响应 WebSocket Ping 帧，由客户端定期发送 HEARTBEAT_REQ 保活，读超时按心跳间隔计算
+type HeartbeatConfig struct {
+	Interval   time.Duration `yaml:"interval"`   // 客户端发送 HEARTBEAT_REQ 的间隔，未配置时为 15s
+	MaxMissed  int           `yaml:"maxMissed"`  // 允许连续丢失的心跳数，未配置时为 2
+	PingPeriod time.Duration `yaml:"pingPeriod"` // 服务端发送 WebSocket Ping 的周期，需小于读超时，<= 0 时不发送
+}
+
+// ReadDeadline 读超时，连续 MaxMissed 次心跳未到达后的下一个心跳间隔内仍未收到消息则断开
+func (c HeartbeatConfig) ReadDeadline() time.Duration {
+	return c.Interval * time.Duration(c.MaxMissed+1)
 }
 
 // MongoConfig MongoDB配置
@@ -25,6 +97,44 @@ type MongoConfig struct {
 	OpTimeout   time.Duration `yaml:"opTimeout"`
 }
 
+// SessionConfig 会话配置
+type SessionConfig struct {
+	TTL time.Duration `yaml:"ttl"` // 会话令牌有效期
+}
+
+// TransferConfig 分片传输配置
+type TransferConfig struct {
+	Dir       string        `yaml:"dir"`       // 临时文件目录
+	MaxSize   int64         `yaml:"maxSize"`   // 单个传输的最大字节数
+	ChunkSize int           `yaml:"chunkSize"` // 单个分片的最大字节数
+	TTL       time.Duration `yaml:"ttl"`       // 传输无进展后的保留时长，超时删除临时文件
+	// 以下上限在开始新传输时检查，< 0 表示不限制；字节数按客户端声明的文件大小计算，包括已完成但尚未删除的传输
+	MaxActivePerUser int   `yaml:"maxActivePerUser"` // 单个用户未完成的传输数
+	MaxBytesPerUser  int64 `yaml:"maxBytesPerUser"`  // 单个用户所有传输的总字节数
+	MaxTotalBytes    int64 `yaml:"maxTotalBytes"`    // 所有用户传输的总字节数，限制临时目录占用
+}
+
+// PushConfig 服务端推送配置
+type PushConfig struct {
+	TTL           time.Duration `yaml:"ttl"`           // 推送有效期，过期未确认的推送不再投递
+	RetryInterval time.Duration `yaml:"retryInterval"` // 未确认推送的首次重试间隔，之后按指数退避
+	MaxRetries    int           `yaml:"maxRetries"`    // 在线期间的最大重试次数，超过后等待下次登录再投递
+}
+
+// VersionRule 客户端版本规则，Platform / EnvVersion 为空时匹配任意值
+type VersionRule struct {
+	Platform      string `yaml:"platform"`      // 客户端平台，如 ios、android、devtools
+	EnvVersion    string `yaml:"envVersion"`    // 小程序环境: develop, trial, release
+	MinVersion    int32  `yaml:"minVersion"`    // 最低支持版本，低于该版本必须升级
+	LatestVersion int32  `yaml:"latestVersion"` // 最新版本，低于该版本提示升级
+	Message       string `yaml:"message"`       // 升级提示，为空时使用默认提示
+}
+
+// VersionConfig 客户端版本策略，同时匹配平台和环境的规则优先
+type VersionConfig struct {
+	Rules []VersionRule `yaml:"rules"`
+}
+
 // LogConfig 日志配置
 type LogConfig struct {
 	Level string `yaml:"level"`
@@ -33,9 +143,13 @@ type LogConfig struct {
 
 // Config 总配置
 type Config struct {
-	Server  ServerConfig `yaml:"server"`
-	MongoDB MongoConfig  `yaml:"mongodb"`
-	Log     LogConfig    `yaml:"logger"`
+	Server   ServerConfig   `yaml:"server"`
+	MongoDB  MongoConfig    `yaml:"mongodb"`
+	Log      LogConfig      `yaml:"logger"`
+	Session  SessionConfig  `yaml:"session"`
+	Transfer TransferConfig `yaml:"transfer"`
+	Version  VersionConfig  `yaml:"version"`
+	Push     PushConfig     `yaml:"push"`
 }
 
 var Cfg Config
@@ -59,4 +173,55 @@ func LoadConfig(path string) {
 	if Cfg.MongoDB.OpTimeout == 0 {
 		Cfg.MongoDB.OpTimeout = 5 * time.Second
 	}
+	// 设置默认会话有效期
+	if Cfg.Session.TTL == 0 {
+		Cfg.Session.TTL = 7 * 24 * time.Hour
+	}
+	// 设置默认分片传输配置
+	if Cfg.Transfer.Dir == "" {
+		Cfg.Transfer.Dir = os.TempDir()
+	}
+	if Cfg.Transfer.MaxSize == 0 {
+		Cfg.Transfer.MaxSize = 20 << 20
+	}
+	if Cfg.Transfer.ChunkSize == 0 {
+		Cfg.Transfer.ChunkSize = 64 << 10
+	}
+	if Cfg.Transfer.TTL == 0 {
+		Cfg.Transfer.TTL = 24 * time.Hour
+	}
+	if Cfg.Transfer.MaxActivePerUser == 0 {
+		Cfg.Transfer.MaxActivePerUser = 4
+	}
+	if Cfg.Transfer.MaxBytesPerUser == 0 {
+		Cfg.Transfer.MaxBytesPerUser = 100 << 20
+	}
+	if Cfg.Transfer.MaxTotalBytes == 0 {
+		Cfg.Transfer.MaxTotalBytes = 2 << 30
+	}
+	// 设置默认推送配置
+	if Cfg.Push.TTL == 0 {
+		Cfg.Push.TTL = 7 * 24 * time.Hour
+	}
+	if Cfg.Push.RetryInterval == 0 {
+		Cfg.Push.RetryInterval = 10 * time.Second
+	}
+	if Cfg.Push.MaxRetries == 0 {
+		Cfg.Push.MaxRetries = 5
+	}
+	// 设置默认请求处理超时
+	if Cfg.Server.Request.Timeout == 0 {
+		Cfg.Server.Request.Timeout = 30 * time.Second
+	}
+	// 设置默认心跳配置
+	if Cfg.Server.Heartbeat.Interval == 0 {
+		Cfg.Server.Heartbeat.Interval = 15 * time.Second
+	}
+	if Cfg.Server.Heartbeat.MaxMissed == 0 {
+		Cfg.Server.Heartbeat.MaxMissed = 2
+	}
+	// 设置默认优雅关闭超时
+	if Cfg.Server.ShutdownTimeout == 0 {
+		Cfg.Server.ShutdownTimeout = 10 * time.Second
+	}
 }
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// 握手时通过 Sec-WebSocket-Protocol 请求头选择的子协议
+const (
+	SubprotocolProtobuf = "protobuf" // 二进制帧，protobuf 编码（默认）
+	SubprotocolJSON     = "json"     // 文本帧，protojson 编码，data 为内嵌的 JSON 对象，便于调试
+)
+
+// Subprotocols 服务端支持的子协议，按优先级排列
+var Subprotocols = []string{SubprotocolProtobuf, SubprotocolJSON}
+
+// codec 消息编解码，每个连接在建立时根据协商的子协议确定
+type codec interface {
+	// decodeRequest 解析基础请求，dataType 返回协议类型对应的具体请求类型
+	// 基础请求已解析但请求数据无效时，同时返回基础请求和错误，以便错误响应携带请求ID
+	decodeRequest(msg []byte, dataType func(model.ProtocolType) (protoreflect.MessageType, bool)) (*model.BaseRequest, error)
+	// encodeResponse 编码基础响应，data 为 resp.Data 对应的具体响应，可以为 nil
+	encodeResponse(resp *model.BaseResponse, data proto.Message) (*wshub.Message, error)
+}
+
+var (
+	protobufCodec codec = binaryCodec{}
+	jsonCodec     codec = textCodec{}
+)
+
+// codecOf 获取客户端使用的编解码
+func codecOf(client wshub.IClient) codec {
+	if client.GetContextString(ContextKeySubprotocol) == SubprotocolJSON {
+		return jsonCodec
+	}
+	return protobufCodec
+}
+
+// noRequestType 不解析请求数据，用于只需要基础请求字段的场景
+func noRequestType(model.ProtocolType) (protoreflect.MessageType, bool) {
+	return nil, false
+}
+
+// isJSONMessage 判断消息是否为文本编码，protobuf 编码的 BaseRequest 不会以 '{' 开头
+func isJSONMessage(msg []byte) bool {
+	trimmed := bytes.TrimLeft(msg, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// binaryCodec protobuf 二进制编解码
+type binaryCodec struct{}
+
+func (binaryCodec) decodeRequest(msg []byte, _ func(model.ProtocolType) (protoreflect.MessageType, bool)) (*model.BaseRequest, error) {
+	var baseReq model.BaseRequest
+	if err := proto.Unmarshal(msg, &baseReq); err != nil {
+		return nil, err
+	}
+	return &baseReq, nil
+}
+
+func (binaryCodec) encodeResponse(resp *model.BaseResponse, _ proto.Message) (*wshub.Message, error) {
+	respBytes, err := proto.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return wshub.NewBinaryMessage(respBytes), nil
+}
+
+// textCodec protojson 文本编解码
+// 基础请求和响应的 data 字段不再是 base64 字节串，而是具体协议的 JSON 对象，例如
+// {"type":"LOGIN_REQ","requestId":"1","data":{"jsCode":"xxx"}}
+type textCodec struct{}
+
+func (textCodec) decodeRequest(msg []byte, dataType func(model.ProtocolType) (protoreflect.MessageType, bool)) (*model.BaseRequest, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return nil, err
+	}
+	data := fields["data"]
+	delete(fields, "data")
+	envelope, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseReq model.BaseRequest
+	if err := protojson.Unmarshal(envelope, &baseReq); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return &baseReq, nil
+	}
+	messageType, ok := dataType(baseReq.Type)
+	if !ok {
+		// 未注册的协议类型不解析 data，由分发时返回未知协议错误
+		return &baseReq, nil
+	}
+	req := messageType.New().Interface()
+	if err := protojson.Unmarshal(data, req); err != nil {
+		return &baseReq, fmt.Errorf("unmarshal %s data: %w", baseReq.Type, err)
+	}
+	if baseReq.Data, err = proto.Marshal(req); err != nil {
+		return nil, err
+	}
+	return &baseReq, nil
+}
+
+func (textCodec) encodeResponse(resp *model.BaseResponse, data proto.Message) (*wshub.Message, error) {
+	envelope := proto.Clone(resp).(*model.BaseResponse)
+	envelope.Data = nil
+	envelopeBytes, err := protojson.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return wshub.NewTextMessage(envelopeBytes), nil
+	}
+
+	dataBytes, err := protojson.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(envelopeBytes, &fields); err != nil {
+		return nil, err
+	}
+	fields["data"] = dataBytes
+	respBytes, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return wshub.NewTextMessage(respBytes), nil
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"encoding/json"
+	"happyAssistant/internal/model"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// loginRequestType 只注册登录请求的请求类型
+func loginRequestType(protocolType model.ProtocolType) (protoreflect.MessageType, bool) {
+	if protocolType == model.ProtocolType_LOGIN_REQ {
+		return (&model.LoginRequest{}).ProtoReflect().Type(), true
+	}
+	return nil, false
+}
+
+func TestTextCodecDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       string
+		wantType  model.ProtocolType
+		wantID    uint64
+		wantData  proto.Message // nil 表示 data 为空
+		wantError bool
+		wantBase  bool // 出错时是否仍返回基础请求
+	}{
+		{
+			name:     "login with data",
+			msg:      `{"type":"LOGIN_REQ","requestId":"7","data":{"jsCode":"code","version":120}}`,
+			wantType: model.ProtocolType_LOGIN_REQ, wantID: 7,
+			wantData: &model.LoginRequest{JsCode: "code", Version: 120},
+		},
+		{
+			name:     "null data",
+			msg:      `{"type":"LOGIN_REQ","requestId":"8","data":null}`,
+			wantType: model.ProtocolType_LOGIN_REQ, wantID: 8,
+		},
+		{
+			name:     "unregistered type keeps data empty",
+			msg:      `{"type":"HEARTBEAT_REQ","requestId":"9","data":{"clientTime":"1"}}`,
+			wantType: model.ProtocolType_HEARTBEAT_REQ, wantID: 9,
+		},
+		{
+			name:     "invalid data",
+			msg:      `{"type":"LOGIN_REQ","requestId":"10","data":{"unknown":1}}`,
+			wantType: model.ProtocolType_LOGIN_REQ, wantID: 10,
+			wantError: true, wantBase: true,
+		},
+		{name: "invalid envelope", msg: `{"type":"NOPE"}`, wantError: true},
+		{name: "invalid json", msg: `{`, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseReq, err := jsonCodec.decodeRequest([]byte(tt.msg), loginRequestType)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("decodeRequest() error = %v, want error %v", err, tt.wantError)
+			}
+			if tt.wantError && !tt.wantBase {
+				if baseReq != nil {
+					t.Errorf("decodeRequest() base = %v, want nil", baseReq)
+				}
+				return
+			}
+			if baseReq.GetType() != tt.wantType || baseReq.GetRequestId() != tt.wantID {
+				t.Errorf("type, id = %v, %d, want %v, %d", baseReq.GetType(), baseReq.GetRequestId(), tt.wantType, tt.wantID)
+			}
+			if tt.wantError {
+				return
+			}
+			if tt.wantData == nil {
+				if len(baseReq.Data) != 0 {
+					t.Errorf("data = %x, want empty", baseReq.Data)
+				}
+				return
+			}
+			got := tt.wantData.ProtoReflect().New().Interface()
+			if err := proto.Unmarshal(baseReq.Data, got); err != nil {
+				t.Fatalf("unmarshal data: %v", err)
+			}
+			if !proto.Equal(got, tt.wantData) {
+				t.Errorf("data = %v, want %v", got, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestCodecEncodeResponseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data proto.Message
+	}{
+		{name: "with data", data: &model.HeartbeatResponse{ServerTime: 2, ClientTime: 1, Counters: map[string]int32{"unread": 3}}},
+		{name: "without data"},
+	}
+	for _, tt := range tests {
+		for _, c := range []struct {
+			name  string
+			codec codec
+			kind  int
+		}{
+			{name: "protobuf", codec: protobufCodec, kind: websocket.BinaryMessage},
+			{name: "json", codec: jsonCodec, kind: websocket.TextMessage},
+		} {
+			t.Run(tt.name+"/"+c.name, func(t *testing.T) {
+				resp := &model.BaseResponse{
+					Type:      model.ProtocolType_HEARTBEAT_RESP,
+					Result:    model.RESP_CODE_SUCCESS,
+					RequestId: 5,
+					Timestamp: 100,
+				}
+				if tt.data != nil {
+					var err error
+					if resp.Data, err = proto.Marshal(tt.data); err != nil {
+						t.Fatalf("marshal data: %v", err)
+					}
+				}
+				frame, err := c.codec.encodeResponse(resp, tt.data)
+				if err != nil {
+					t.Fatalf("encodeResponse: %v", err)
+				}
+				if frame.Type != c.kind {
+					t.Errorf("frame type = %d, want %d", frame.Type, c.kind)
+				}
+				if c.kind == websocket.TextMessage && !json.Valid(frame.Data) {
+					t.Fatalf("frame is not valid JSON: %s", frame.Data)
+				}
+
+				var data proto.Message
+				if tt.data != nil {
+					data = tt.data.ProtoReflect().New().Interface()
+				}
+				got := decodeResponse(t, frame, data)
+				if got.GetType() != resp.Type || got.GetResult() != resp.Result ||
+					got.GetRequestId() != resp.RequestId || got.GetTimestamp() != resp.Timestamp {
+					t.Errorf("response = %v, want %v", got, resp)
+				}
+				if tt.data != nil && !proto.Equal(data, tt.data) {
+					t.Errorf("data = %v, want %v", data, tt.data)
+				}
+			})
+		}
+	}
+}
+
+func TestIsJSONMessage(t *testing.T) {
+	binary, err := proto.Marshal(&model.BaseRequest{Type: model.ProtocolType_LOGIN_REQ, RequestId: 1})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	tests := []struct {
+		name string
+		msg  []byte
+		want bool
+	}{
+		{name: "json", msg: []byte(`{"type":"LOGIN_REQ"}`), want: true},
+		{name: "json with leading whitespace", msg: []byte(" \r\n{}"), want: true},
+		{name: "protobuf", msg: binary},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		if got := isJSONMessage(tt.msg); got != tt.want {
+			t.Errorf("%s: isJSONMessage() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
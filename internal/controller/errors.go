@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"fmt"
+	"happyAssistant/internal/apperror"
+	"happyAssistant/internal/model"
+	"happyAssistant/internal/service"
+)
+
+// 控制器返回的应用错误
+var (
+	errInvalidRequest   = apperror.New(model.ErrorCode_INVALID_ARGUMENT, "Invalid request format")
+	errUnknownProtocol  = apperror.New(model.ErrorCode_UNKNOWN_PROTOCOL, "Unknown protocol type")
+	errNotLoggedIn      = apperror.New(model.ErrorCode_NOT_LOGGED_IN, "Not logged in")
+	errPermissionDenied = apperror.New(model.ErrorCode_PERMISSION_DENIED, "Permission denied")
+	errTooManyRequests  = apperror.New(model.ErrorCode_RATE_LIMITED, "Too many requests")
+	errUnavailable      = apperror.New(model.ErrorCode_UNAVAILABLE, "Service temporarily unavailable, please retry later")
+)
+
+// appUpdateError 客户端版本低于最低支持版本，以 APP_UPDATE 响应返回升级信息
+type appUpdateError struct {
+	info *model.AppUpdateInfo
+}
+
+func (e *appUpdateError) Error() string {
+	return fmt.Sprintf("client version below minimum %d: %s", e.info.MinVersion, e.info.Message)
+}
+
+// serviceErrors 服务层错误到应用错误的映射，未列出的错误按内部错误处理，
+// 新增服务错误需要返回给客户端时在此添加
+var serviceErrors = []apperror.Mapping{
+	{Target: service.ErrInvalidJsCode, Code: model.ErrorCode_INVALID_ARGUMENT, Message: "Invalid login code"},
+	{Target: service.ErrSessionInvalid, Code: model.ErrorCode_NOT_LOGGED_IN, Message: "Session is invalid or expired"},
+	{Target: service.ErrTransferNotFound, Code: model.ErrorCode_NOT_FOUND, Message: "Transfer not found or expired"},
+	{Target: service.ErrTransferTooLarge, Code: model.ErrorCode_PAYLOAD_TOO_LARGE, Message: "Transfer size exceeds limit"},
+	{Target: service.ErrTransferChunkSize, Code: model.ErrorCode_PAYLOAD_TOO_LARGE, Message: "Chunk size exceeds limit"},
+	{Target: service.ErrTransferChecksum, Code: model.ErrorCode_INVALID_ARGUMENT, Message: "Checksum mismatch"},
+	{Target: service.ErrTransferOffset, Code: model.ErrorCode_FAILED_PRECONDITION, Message: "Unexpected chunk offset"},
+	{Target: service.ErrTransferIncomplete, Code: model.ErrorCode_FAILED_PRECONDITION, Message: "Transfer is incomplete"},
+	{Target: service.ErrTransferLimit, Code: model.ErrorCode_RATE_LIMITED, Message: "Too many or too large transfers in progress"},
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"happyAssistant/internal/apperror"
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Request 协议请求上下文
+type Request struct {
+	Client        wshub.IClient
+	Base          *model.BaseRequest
+	ctx           context.Context
+	afterResponse []func()
+}
+
+// Context 获取请求的上下文，客户端断开、超过协议类型的处理超时或被 CANCEL_REQ 取消时结束，
+// 调用服务层和数据库操作时传入
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// AfterResponse 注册在成功响应发送之后执行的函数，按注册顺序在处理协程中执行，失败响应后不执行
+// 用于需要排在响应之后发送给客户端的消息，例如登录后投递未确认的推送
+func (r *Request) AfterResponse(fn func()) {
+	r.afterResponse = append(r.afterResponse, fn)
+}
+
+// UserID 获取当前登录用户ID，未登录时返回空字符串
+func (r *Request) UserID() string {
+	return r.Client.GetContextString(ContextKeyUserID)
+}
+
+// Permissions 获取当前用户在所在实验室的权限标志位
+func (r *Request) Permissions() uint64 {
+	permissions, _ := r.Client.GetContextValue(ContextKeyPermissions).(uint64)
+	return permissions
+}
+
+// HandlerFunc 协议处理函数
+// req 为已解析的具体请求，返回的响应自动序列化并以对应的 _RESP 类型发送；
+// 返回错误时以请求类型发送错误响应
+type HandlerFunc[Req, Resp proto.Message] func(r *Request, req Req) (Resp, error)
+
+// route 已注册的协议处理器
+type route struct {
+	reqType     protoreflect.MessageType
+	respType    model.ProtocolType
+	handle      Handler
+	middlewares []Middleware
+}
+
+// HandlerRegistry 按协议类型注册的处理器
+// 注册在启动阶段完成，之后只读，并发分发无需加锁
+type HandlerRegistry struct {
+	routes      map[model.ProtocolType]*route
+	middlewares []Middleware
+}
+
+// NewHandlerRegistry 创建处理器注册表
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{routes: make(map[model.ProtocolType]*route)}
+}
+
+// Use 添加全局中间件，作用于所有协议类型，先添加的在外层，且在协议类型的中间件之外
+func (registry *HandlerRegistry) Use(middlewares ...Middleware) {
+	registry.middlewares = append(registry.middlewares, middlewares...)
+}
+
+// Register 注册协议处理器，响应类型为请求类型对应的 XXX_RESP，middlewares 只作用于该协议类型
+// 重复注册或请求类型没有对应的响应类型时 panic
+func Register[Req, Resp proto.Message](registry *HandlerRegistry, reqType model.ProtocolType, handler HandlerFunc[Req, Resp], middlewares ...Middleware) {
+	if _, ok := registry.routes[reqType]; ok {
+		panic(fmt.Sprintf("controller: handler for %s already registered", reqType))
+	}
+	respType, ok := responseTypeOf(reqType)
+	if !ok {
+		panic(fmt.Sprintf("controller: no response type for %s", reqType))
+	}
+
+	var zero Req
+	messageType := zero.ProtoReflect().Type()
+	registry.routes[reqType] = &route{
+		reqType:     messageType,
+		respType:    respType,
+		middlewares: middlewares,
+		handle: func(r *Request) (proto.Message, error) {
+			req := messageType.New().Interface().(Req)
+			if err := proto.Unmarshal(r.Base.Data, req); err != nil {
+				return nil, apperror.Wrap(err, model.ErrorCode_INVALID_ARGUMENT, fmt.Sprintf("Invalid %s request format", reqType))
+			}
+			return handler(r, req)
+		},
+	}
+}
+
+// lookup 获取协议类型对应的处理器及响应类型，处理器已包装全局及该协议类型的中间件
+func (registry *HandlerRegistry) lookup(protocolType model.ProtocolType) (Handler, model.ProtocolType, bool) {
+	rt, ok := registry.routes[protocolType]
+	if !ok {
+		return nil, model.ProtocolType_UNKNOWN, false
+	}
+	return chain(chain(rt.handle, rt.middlewares...), registry.middlewares...), rt.respType, true
+}
+
+// requestType 获取协议类型注册的具体请求类型，供文本编解码解析内嵌的请求数据
+func (registry *HandlerRegistry) requestType(protocolType model.ProtocolType) (protoreflect.MessageType, bool) {
+	rt, ok := registry.routes[protocolType]
+	if !ok {
+		return nil, false
+	}
+	return rt.reqType, true
+}
+
+// responseTypeOf 获取请求类型对应的响应类型，XXX_REQ 对应 XXX_RESP
+func responseTypeOf(protocolType model.ProtocolType) (model.ProtocolType, bool) {
+	name := protocolType.String()
+	if !strings.HasSuffix(name, "_REQ") {
+		return protocolType, false
+	}
+	value, ok := model.ProtocolType_value[strings.TrimSuffix(name, "_REQ")+"_RESP"]
+	return model.ProtocolType(value), ok
+}
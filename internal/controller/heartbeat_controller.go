@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+	"time"
+)
+
+// HeartbeatCounter 心跳响应携带的计数，例如未读消息数
+// 每次心跳都会调用，应只读取内存中的状态，不访问数据库或业务服务
+type HeartbeatCounter func(client wshub.IClient) int32
+
+// SetHeartbeatCounter 设置心跳响应中名为 name 的计数，需在启动阶段、开始处理连接之前设置
+func (pc *ProtocolController) SetHeartbeatCounter(name string, counter HeartbeatCounter) {
+	if pc.heartbeatCounters == nil {
+		pc.heartbeatCounters = make(map[string]HeartbeatCounter)
+	}
+	pc.heartbeatCounters[name] = counter
+}
+
+// handleHeartbeatRequest 处理心跳请求
+// 收到任意消息时连接的读超时都会重置，这里只返回服务端时间和计数，不经过业务服务，未登录也可以发送
+func (pc *ProtocolController) handleHeartbeatRequest(r *Request, req *model.HeartbeatRequest) (*model.HeartbeatResponse, error) {
+	resp := &model.HeartbeatResponse{
+		ServerTime: time.Now().UnixMilli(),
+		ClientTime: req.ClientTime,
+	}
+	for name, counter := range pc.heartbeatCounters {
+		if count := counter(r.Client); count != 0 {
+			if resp.Counters == nil {
+				resp.Counters = make(map[string]int32, len(pc.heartbeatCounters))
+			}
+			resp.Counters[name] = count
+		}
+	}
+	return resp, nil
+}
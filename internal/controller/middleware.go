@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"fmt"
+	"happyAssistant/internal/apperror"
+	"happyAssistant/internal/model"
+	"happyAssistant/internal/service"
+	"happyAssistant/pkg/wshub"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+// Handler 协议处理器，返回的响应以对应的 _RESP 类型发送，错误以请求类型发送错误响应
+type Handler func(r *Request) (proto.Message, error)
+
+// Middleware 协议处理中间件，包装下一个处理器
+type Middleware func(next Handler) Handler
+
+// chain 按顺序组合中间件，第一个中间件在最外层
+func chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Recovery 捕获处理器中的 panic 并返回错误响应，避免中断客户端的读协程
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) (resp proto.Message, err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					log.Errorf("Handler panic, protocol type: %v, user: %s: %v\n%s",
+						r.Base.Type, r.UserID(), p, debug.Stack())
+					resp, err = nil, apperror.Wrap(fmt.Errorf("panic: %v", p), model.ErrorCode_INTERNAL, "Internal server error")
+				}
+			}()
+			return next(r)
+		}
+	}
+}
+
+// Logging 记录请求的处理耗时与结果，超过 slow 的请求以警告级别记录，slow <= 0 时不区分慢请求
+func Logging(slow time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) (proto.Message, error) {
+			start := time.Now()
+			resp, err := next(r)
+			elapsed := time.Since(start)
+			switch {
+			case err != nil:
+				log.Warnf("Request failed, protocol type: %v, request id: %d, user: %s, elapsed: %v: %v",
+					r.Base.Type, r.Base.RequestId, r.UserID(), elapsed, err)
+			case slow > 0 && elapsed > slow:
+				log.Warnf("Slow request, protocol type: %v, request id: %d, user: %s, elapsed: %v",
+					r.Base.Type, r.Base.RequestId, r.UserID(), elapsed)
+			default:
+				log.Debugf("Request handled, protocol type: %v, request id: %d, user: %s, elapsed: %v",
+					r.Base.Type, r.Base.RequestId, r.UserID(), elapsed)
+			}
+			return resp, err
+		}
+	}
+}
+
+// RequireVersion 按版本策略检查客户端版本，低于最低支持版本时返回 APP_UPDATE 响应
+// 客户端未上报版本（未在握手时携带版本参数，且未登录或会话中没有版本信息）时按版本 0 检查，
+// 匹配的规则设置了最低支持版本时同样需要升级
+func RequireVersion(versions *service.VersionService) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) (proto.Message, error) {
+			info := versions.Check(r.Client.GetContextString(ContextKeyPlatform),
+				r.Client.GetContextString(ContextKeyEnvVersion), int32(r.Client.GetContextInt(ContextKeyVersion)))
+			if info.GetForce() {
+				return nil, &appUpdateError{info: info}
+			}
+			return next(r)
+		}
+	}
+}
+
+// RequireLogin 要求客户端已登录
+func RequireLogin() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) (proto.Message, error) {
+			if r.UserID() == "" {
+				return nil, errNotLoggedIn
+			}
+			return next(r)
+		}
+	}
+}
+
+// RequirePermission 要求当前用户在所在实验室的角色拥有全部指定权限，未登录时返回未登录错误
+func RequirePermission(permissions ...model.Permission) Middleware {
+	var required uint64
+	for _, permission := range permissions {
+		required |= uint64(permission)
+	}
+	return func(next Handler) Handler {
+		return func(r *Request) (proto.Message, error) {
+			if r.UserID() == "" {
+				return nil, errNotLoggedIn
+			}
+			if r.Permissions()&required != required {
+				log.Warnf("Permission denied, user: %s, protocol type: %v", r.UserID(), r.Base.Type)
+				return nil, errPermissionDenied
+			}
+			return next(r)
+		}
+	}
+}
+
+// rateLimitSeq 为每个 RateLimit 中间件分配编号，区分客户端上下文中的令牌桶
+var rateLimitSeq atomic.Uint64
+
+// RateLimit 按客户端限制请求频率，每个 RateLimit 实例在其作用的每个协议类型上独立计数，
+// 全局与协议类型上分别使用的实例互不共享令牌
+// 令牌桶保存在客户端上下文中，随连接释放，会话恢复时一并继承
+func RateLimit(rate float64, burst int) Middleware {
+	var mu sync.Mutex
+	id := rateLimitSeq.Add(1)
+	return func(next Handler) Handler {
+		return func(r *Request) (proto.Message, error) {
+			key := fmt.Sprintf("rate_limit:%d:%s", id, r.Base.Type)
+			mu.Lock()
+			bucket, ok := r.Client.GetContextValue(key).(*wshub.TokenBucket)
+			if !ok {
+				bucket = wshub.NewTokenBucket(rate, burst)
+				r.Client.SetContextValue(key, bucket)
+			}
+			mu.Unlock()
+			if !bucket.Allow() {
+				log.Warnf("Rate limited, user: %s, protocol type: %v", r.UserID(), r.Base.Type)
+				return nil, errTooManyRequests
+			}
+			return next(r)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"errors"
+	"happyAssistant/internal/apperror"
+	"happyAssistant/internal/config"
+	"happyAssistant/internal/model"
+	"happyAssistant/internal/service"
+	"happyAssistant/pkg/wshub/wshubtest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// okHandler 总是成功的处理器
+func okHandler(*Request) (proto.Message, error) {
+	return &model.HeartbeatResponse{}, nil
+}
+
+// newTestRequest 创建指定协议类型的请求
+func newTestRequest(client *wshubtest.Client, protocolType model.ProtocolType) *Request {
+	return &Request{Client: client, Base: &model.BaseRequest{Type: protocolType}}
+}
+
+// errorCode 获取错误对应的错误码，成功时为 NO_ERROR
+func errorCode(err error) model.ErrorCode {
+	if err == nil {
+		return model.ErrorCode_NO_ERROR
+	}
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return model.ErrorCode_INTERNAL
+}
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(r *Request) (proto.Message, error) {
+				calls = append(calls, name)
+				return next(r)
+			}
+		}
+	}
+	handler := chain(okHandler, trace("outer"), trace("inner"))
+	if _, err := handler(newTestRequest(wshubtest.NewClient(), model.ProtocolType_HEARTBEAT_REQ)); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "outer" || calls[1] != "inner" {
+		t.Errorf("calls = %v, want [outer inner]", calls)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	handler := Recovery()(func(*Request) (proto.Message, error) {
+		panic("boom")
+	})
+	resp, err := handler(newTestRequest(wshubtest.NewClient(), model.ProtocolType_HEARTBEAT_REQ))
+	if resp != nil || errorCode(err) != model.ErrorCode_INTERNAL {
+		t.Errorf("Recovery() = %v, %v, want INTERNAL error", resp, err)
+	}
+}
+
+func TestRequireVersion(t *testing.T) {
+	versions := service.NewVersionService(config.VersionConfig{Rules: []config.VersionRule{
+		{MinVersion: 100, LatestVersion: 120},
+		{Platform: "ios", EnvVersion: "develop"},
+	}})
+	tests := []struct {
+		name       string
+		platform   string
+		envVersion string
+		version    int
+		wantUpdate bool
+	}{
+		{name: "missing version below floor", wantUpdate: true},
+		{name: "missing version with platform below floor", platform: "android", envVersion: "release", wantUpdate: true},
+		{name: "below minimum", platform: "android", envVersion: "release", version: 99, wantUpdate: true},
+		{name: "at minimum", platform: "android", envVersion: "release", version: 100},
+		{name: "missing version without floor", platform: "ios", envVersion: "develop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := wshubtest.NewClient()
+			if tt.platform != "" {
+				client.SetContextValue(ContextKeyPlatform, tt.platform)
+				client.SetContextValue(ContextKeyEnvVersion, tt.envVersion)
+			}
+			if tt.version > 0 {
+				client.SetContextValue(ContextKeyVersion, tt.version)
+			}
+			_, err := RequireVersion(versions)(okHandler)(newTestRequest(client, model.ProtocolType_HEARTBEAT_REQ))
+			var updateErr *appUpdateError
+			if got := errors.As(err, &updateErr); got != tt.wantUpdate {
+				t.Errorf("RequireVersion() error = %v, want update %v", err, tt.wantUpdate)
+			}
+		})
+	}
+}
+
+func TestRequireLoginAndPermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		permissions uint64
+		middleware  Middleware
+		want        model.ErrorCode
+	}{
+		{name: "login without user", middleware: RequireLogin(), want: model.ErrorCode_NOT_LOGGED_IN},
+		{name: "login with user", userID: "u1", middleware: RequireLogin()},
+		{name: "permission without user", middleware: RequirePermission(model.Permission_ORDER_CREATE), want: model.ErrorCode_NOT_LOGGED_IN},
+		{name: "permission missing", userID: "u1", permissions: uint64(model.Permission_ORDER_CREATE),
+			middleware: RequirePermission(model.Permission_ORDER_CREATE, model.Permission_ORDER_DELETE), want: model.ErrorCode_PERMISSION_DENIED},
+		{name: "permission granted", userID: "u1", permissions: uint64(model.Permission_ORDER_CREATE | model.Permission_ORDER_DELETE),
+			middleware: RequirePermission(model.Permission_ORDER_CREATE, model.Permission_ORDER_DELETE)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := wshubtest.NewClient()
+			if tt.userID != "" {
+				client.SetContextValue(ContextKeyUserID, tt.userID)
+				client.SetContextValue(ContextKeyPermissions, tt.permissions)
+			}
+			_, err := tt.middleware(okHandler)(newTestRequest(client, model.ProtocolType_HEARTBEAT_REQ))
+			if got := errorCode(err); got != tt.want {
+				t.Errorf("error code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	heartbeat, cancel := model.ProtocolType_HEARTBEAT_REQ, model.ProtocolType_CANCEL_REQ
+	tests := []struct {
+		name string
+		// handlers 返回每次调用使用的处理器，用于组合不同的 RateLimit 实例
+		handlers func() []Handler
+		types    []model.ProtocolType
+		want     []model.ErrorCode
+	}{
+		{
+			name: "burst then limited",
+			handlers: func() []Handler {
+				h := RateLimit(0.001, 2)(okHandler)
+				return []Handler{h, h, h}
+			},
+			types: []model.ProtocolType{heartbeat, heartbeat, heartbeat},
+			want:  []model.ErrorCode{model.ErrorCode_NO_ERROR, model.ErrorCode_NO_ERROR, model.ErrorCode_RATE_LIMITED},
+		},
+		{
+			name: "protocol types counted separately",
+			handlers: func() []Handler {
+				h := RateLimit(0.001, 1)(okHandler)
+				return []Handler{h, h, h}
+			},
+			types: []model.ProtocolType{heartbeat, cancel, heartbeat},
+			want:  []model.ErrorCode{model.ErrorCode_NO_ERROR, model.ErrorCode_NO_ERROR, model.ErrorCode_RATE_LIMITED},
+		},
+		{
+			name: "instances do not share buckets",
+			handlers: func() []Handler {
+				strict := RateLimit(0.001, 1)(okHandler)
+				loose := RateLimit(0.001, 3)(okHandler)
+				return []Handler{strict, loose, loose, strict}
+			},
+			types: []model.ProtocolType{heartbeat, heartbeat, heartbeat, heartbeat},
+			want: []model.ErrorCode{model.ErrorCode_NO_ERROR, model.ErrorCode_NO_ERROR, model.ErrorCode_NO_ERROR,
+				model.ErrorCode_RATE_LIMITED},
+		},
+		{
+			name: "global and per-protocol limits stack",
+			handlers: func() []Handler {
+				// 全局 burst 为 2，协议类型上的 burst 为 3，两者各自计数，较严格的先触发
+				h := chain(okHandler, RateLimit(0.001, 2), RateLimit(0.001, 3))
+				return []Handler{h, h, h}
+			},
+			types: []model.ProtocolType{heartbeat, heartbeat, heartbeat},
+			want:  []model.ErrorCode{model.ErrorCode_NO_ERROR, model.ErrorCode_NO_ERROR, model.ErrorCode_RATE_LIMITED},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := wshubtest.NewClient()
+			for i, handler := range tt.handlers() {
+				_, err := handler(newTestRequest(client, tt.types[i]))
+				if got := errorCode(err); got != tt.want[i] {
+					t.Errorf("call %d error code = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitPerClient(t *testing.T) {
+	handler := RateLimit(0.001, 1)(okHandler)
+	for i, client := range []*wshubtest.Client{wshubtest.NewClient(), wshubtest.NewClient()} {
+		if _, err := handler(newTestRequest(client, model.ProtocolType_HEARTBEAT_REQ)); err != nil {
+			t.Errorf("client %d first request: %v", i, err)
+		}
+	}
+}
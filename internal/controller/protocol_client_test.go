@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wsclient"
+	"happyAssistant/pkg/wshub/wshubtest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer 启动接入控制器的测试服务端，与 cmd/server 的回调设置一致
+func newTestServer(t *testing.T, pc *ProtocolController, opts ...wshub.ClientOption) *wshubtest.Server {
+	t.Helper()
+	hub, err := wshub.NewHub(wshub.WithSubprotocols(Subprotocols...))
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	hub.SetClientOptions(opts...)
+	pc.hub = hub
+	hub.SetAuthenticator(pc.Authenticate)
+	hub.OnOpen = pc.HandleOpen
+	hub.OnReceive = pc.HandleReceive
+	hub.OnDispatchError = pc.HandleDispatchError
+	hub.OnMessage = pc.HandleMessage
+	hub.OnClose = func(client wshub.IClient, _ wshub.CloseCause) { pc.HandleClose(client) }
+	server := wshubtest.NewServer(hub)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dialProtocol 连接测试服务端，不自动重连
+func dialProtocol(t *testing.T, server *wshubtest.Server, opts ...wsclient.Option) *wsclient.ProtocolClient {
+	t.Helper()
+	opts = append([]wsclient.Option{wsclient.WithoutReconnect()}, opts...)
+	client, err := wsclient.DialProtocol(context.Background(), server.URL, nil, opts...)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := server.WaitOpen(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestProtocolClientHeartbeatKeepsIdleConnection(t *testing.T) {
+	const readDeadline = 300 * time.Millisecond
+	tests := []struct {
+		name           string
+		heartbeat      time.Duration
+		wantDisconnect bool
+	}{
+		{name: "heartbeat", heartbeat: readDeadline / 3},
+		{name: "no heartbeat", heartbeat: 0, wantDisconnect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, newTestController(t), wshub.WithReadDeadline(readDeadline))
+			var disconnected atomic.Bool
+			dialProtocol(t, server, wsclient.WithHeartbeat(tt.heartbeat),
+				wsclient.WithDisconnectHandler(func(error) { disconnected.Store(true) }))
+
+			time.Sleep(4 * readDeadline)
+			if got := disconnected.Load(); got != tt.wantDisconnect {
+				t.Errorf("disconnected = %v, want %v", got, tt.wantDisconnect)
+			}
+		})
+	}
+}
+
+func TestProtocolClientMatchesResponses(t *testing.T) {
+	server := newTestServer(t, newTestController(t))
+	client := dialProtocol(t, server, wsclient.WithHeartbeat(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	errs := make(chan error, 8)
+	for i := int64(1); i <= 8; i++ {
+		go func(clientTime int64) {
+			resp := &model.HeartbeatResponse{}
+			err := client.Call(ctx, model.ProtocolType_HEARTBEAT_REQ, &model.HeartbeatRequest{ClientTime: clientTime}, resp)
+			if err == nil && resp.ClientTime != clientTime {
+				err = fmt.Errorf("call %d got response for %d", clientTime, resp.ClientTime)
+			}
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	// 失败响应同样按请求ID交给请求，并转换为 ResponseError
+	err := client.AckPush(ctx, "p1")
+	var respErr *wsclient.ResponseError
+	if !errors.As(err, &respErr) || respErr.Code != model.ErrorCode_NOT_LOGGED_IN {
+		t.Errorf("AckPush error = %v, want NOT_LOGGED_IN", err)
+	}
+}
+
+func TestProtocolClientCancelSendsCancelRequest(t *testing.T) {
+	pc := newTestController(t)
+	started := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	pc.handlers = NewHandlerRegistry()
+	Register(pc.handlers, model.ProtocolType_CANCEL_REQ, pc.handleCancelRequest)
+	Register(pc.handlers, model.ProtocolType_PUSH_ACK_REQ, func(r *Request, _ *model.PushAckRequest) (*model.PushAckResponse, error) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			handlerErr <- r.Context().Err()
+		case <-time.After(2 * time.Second):
+			handlerErr <- nil
+		}
+		return nil, r.Context().Err()
+	})
+	server := newTestServer(t, pc)
+	// 与 cmd/server 一致，CANCEL_REQ 不排在被取消的请求之后
+	server.Hub.SetDispatcher(wshub.NewDispatcher(2, wshub.WithParallelClassifier(IsParallelSafe)))
+	client := dialProtocol(t, server, wsclient.WithHeartbeat(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if err := client.AckPush(ctx, "p1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("AckPush error = %v, want context.Canceled", err)
+	}
+	select {
+	case err := <-handlerErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler did not return")
+	}
+}
@@ -1,122 +1,427 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"happyAssistant/internal/apperror"
+	"happyAssistant/internal/config"
 	"happyAssistant/internal/model"
 	"happyAssistant/internal/service"
 	"happyAssistant/pkg/wshub"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
 // ProtocolController 协议控制器
 // 负责解析客户端请求协议，并根据协议类型路由到相应的业务处理器
 type ProtocolController struct {
-	userService *service.UserService
+	hub             *wshub.WebSocketHub
+	userService     *service.UserService
+	sessionService  *service.SessionService
+	transferService *service.TransferService
+	versionService  *service.VersionService
+	pushService     *service.PushService
 	// 可以添加其他服务
+	requestConfig     config.RequestConfig // 请求处理超时
+	handlers          *HandlerRegistry
+	heartbeatCounters map[string]HeartbeatCounter // 心跳响应携带的计数，启动阶段设置后只读
 }
 
+// 客户端上下文键
+const (
+	ContextKeyUserID       = "user_id"       // 当前登录用户ID
+	ContextKeyLabID        = "lab_id"        // 当前选中的实验室ID
+	ContextKeySessionToken = "session_token" // 当前会话令牌
+	ContextKeyPermissions  = "permissions"   // 当前用户在所在实验室的权限标志位（uint64）
+	ContextKeyPlatform     = "platform"      // 客户端平台
+	ContextKeyEnvVersion   = "env_version"   // 小程序环境
+	ContextKeyVersion      = "version"       // 小程序版本（int）
+	ContextKeySubprotocol  = "subprotocol"   // 握手时协商的子协议，决定消息编码
+)
+
+// slowRequestThreshold 处理耗时超过该值的请求以警告级别记录
+const slowRequestThreshold = time.Second
+
 // NewProtocolController 创建协议控制器实例
-func NewProtocolController() *ProtocolController {
-	return &ProtocolController{
-		userService: service.NewUserService(),
+func NewProtocolController(hub *wshub.WebSocketHub) *ProtocolController {
+	pc := &ProtocolController{
+		hub:             hub,
+		userService:     service.NewUserService(),
+		sessionService:  service.NewSessionService(config.Cfg.Session.TTL),
+		transferService: service.NewTransferService(config.Cfg.Transfer),
+		versionService:  service.NewVersionService(config.Cfg.Version),
+		requestConfig:   config.Cfg.Server.Request,
+	}
+	pc.pushService = service.NewPushService(pc.deliverPush, config.Cfg.Push.TTL,
+		config.Cfg.Push.RetryInterval, config.Cfg.Push.MaxRetries)
+	pc.registerHandlers()
+	return pc
+}
+
+// Close 停止控制器的后台任务
+func (pc *ProtocolController) Close() {
+	pc.pushService.Close()
+}
+
+// registerHandlers 注册所有协议处理器，新增协议时在此注册
+func (pc *ProtocolController) registerHandlers() {
+	pc.handlers = NewHandlerRegistry()
+	pc.handlers.Use(Recovery(), Logging(slowRequestThreshold))
+	// 登录请求自行检查请求中携带的版本，其余请求按连接记录的版本检查，未上报版本按低于最低支持版本处理；
+	// 心跳只用于保活，不检查版本，避免需要升级的客户端在提示升级期间被读超时断开
+	versioned := RequireVersion(pc.versionService)
+
+	Register(pc.handlers, model.ProtocolType_LOGIN_REQ, pc.handleLoginRequest)
+	Register(pc.handlers, model.ProtocolType_TRANSFER_BEGIN_REQ, pc.handleTransferBeginRequest, versioned, RequireLogin())
+	Register(pc.handlers, model.ProtocolType_TRANSFER_CHUNK_REQ, pc.handleTransferChunkRequest, versioned, RequireLogin())
+	Register(pc.handlers, model.ProtocolType_TRANSFER_FINISH_REQ, pc.handleTransferFinishRequest, versioned, RequireLogin())
+	Register(pc.handlers, model.ProtocolType_PUSH_ACK_REQ, pc.handlePushAckRequest, versioned, RequireLogin())
+	Register(pc.handlers, model.ProtocolType_HEARTBEAT_REQ, pc.handleHeartbeatRequest)
+	Register(pc.handlers, model.ProtocolType_CANCEL_REQ, pc.handleCancelRequest, versioned)
+}
+
+// Authenticate 握手鉴权
+// 客户端重连时通过 Authorization: Bearer 头携带会话令牌，校验通过后直接恢复登录状态；
+// 未携带令牌或令牌无效、已过期的连接按未登录处理，允许升级，之后需要发送登录请求。
+// 客户端可通过 platform、env_version、version 查询参数上报版本信息，登录前的请求也会按版本策略检查；
+// 使用会话令牌重连且未携带版本参数时，沿用登录时记录在会话中的版本信息
+func (pc *ProtocolController) Authenticate(r *http.Request) (map[string]interface{}, error) {
+	query := r.URL.Query()
+	values := make(map[string]interface{})
+
+	if token := sessionTokenOf(r); token != "" {
+		session, err := pc.sessionService.Validate(token)
+		if err != nil {
+			log.Infof("Session token rejected, continue as anonymous: %v", err)
+		} else {
+			values[ContextKeyUserID] = session.UserID
+			values[ContextKeyLabID] = session.LabID
+			values[ContextKeySessionToken] = session.Token
+			values[ContextKeyPermissions] = session.Permissions
+			if session.Version > 0 {
+				values[ContextKeyPlatform] = session.Platform
+				values[ContextKeyEnvVersion] = session.EnvVersion
+				values[ContextKeyVersion] = int(session.Version)
+			}
+		}
+	}
+
+	// 握手参数中的版本信息比会话中记录的更新，客户端可能在两次连接之间升级
+	if version, err := strconv.Atoi(query.Get(ContextKeyVersion)); err == nil {
+		values[ContextKeyPlatform] = query.Get(ContextKeyPlatform)
+		values[ContextKeyEnvVersion] = query.Get(ContextKeyEnvVersion)
+		values[ContextKeyVersion] = version
+	}
+	return values, nil
+}
+
+// sessionTokenOf 获取握手请求携带的会话令牌，优先使用 Authorization: Bearer 头，不是 Bearer 方案的头忽略；
+// 无法设置请求头的客户端（例如浏览器 WebSocket）可以使用 token 查询参数，
+// 但查询参数会随 URL 出现在代理和访问日志中，令牌有效期内泄露即可被冒用
+func sessionTokenOf(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get("token")
+}
+
+// HandleOpen 处理新连接，记录协商的子协议并创建连接的请求上下文；
+// 握手鉴权已恢复登录状态时加入所在实验室的推送主题，并投递未确认的推送
+func (pc *ProtocolController) HandleOpen(client wshub.IClient) {
+	// 恢复的会话继承了旧连接的 Context，旧连接的请求上下文已在关闭时取消，需要重新创建
+	client.SetContextValue(contextKeyRequests, newClientRequests())
+	if base := client.GetBaseClient(); base != nil {
+		client.SetContextValue(ContextKeySubprotocol, base.Subprotocol())
+	}
+	if labID := client.GetContextString(ContextKeyLabID); labID != "" {
+		pc.hub.Join(client, LabTopic(labID))
+	}
+	// 握手时恢复登录状态的连接没有登录响应，会话恢复补发的数据帧在 OnOpen 之前已放入发送队列，推送排在其后
+	if userID := client.GetContextString(ContextKeyUserID); userID != "" {
+		go pc.deliverPendingPushes(connContext(client), userID)
 	}
 }
 
+// LabTopic 获取实验室对应的推送主题
+func LabTopic(labID string) string {
+	return fmt.Sprintf("lab:%s", labID)
+}
+
 // HandleMessage 处理客户端消息
-// 解析基础请求协议，并根据协议类型分发到相应的处理器
+// 解析基础请求协议，并根据协议类型分发到注册的处理器，由处理器的返回值发送成功或错误响应
 func (pc *ProtocolController) HandleMessage(client wshub.IClient, msg []byte) {
-	// 解析基础请求协议
-	var baseReq model.BaseRequest
-	if err := proto.Unmarshal(msg, &baseReq); err != nil {
+	// 按连接协商的编码解析基础请求协议
+	baseReq, err := codecOf(client).decodeRequest(msg, pc.handlers.requestType)
+	if err != nil {
 		log.Errorf("Failed to unmarshal base request: %v", err)
-		pc.sendErrorResponse(client, model.ProtocolType_UNKNOWN, "Invalid request format")
+		forgetRequest(client, baseReq.GetRequestId())
+		pc.sendErrorResponse(client, baseReq.GetRequestId(), baseReq.GetType(), errInvalidRequest)
 		return
 	}
 
 	// 根据协议类型路由到相应的处理器
-	switch baseReq.Type {
-	case model.ProtocolType_LOGIN_REQ:
-		pc.handleLoginRequest(client, baseReq.Data)
-	default:
+	handler, respType, ok := pc.handlers.lookup(baseReq.Type)
+	if !ok {
 		log.Warnf("Unknown protocol type: %v", baseReq.Type)
-		pc.sendErrorResponse(client, baseReq.Type, "Unknown protocol type")
+		forgetRequest(client, baseReq.RequestId)
+		pc.sendErrorResponse(client, baseReq.RequestId, baseReq.Type, errUnknownProtocol)
+		return
 	}
-}
-
-// handleLoginRequest 处理登录请求
-func (pc *ProtocolController) handleLoginRequest(client wshub.IClient, data []byte) {
-	// 解析登录请求
-	var loginReq model.LoginRequest
-	if err := proto.Unmarshal(data, &loginReq); err != nil {
-		log.Errorf("Failed to unmarshal login request: %v", err)
-		pc.sendErrorResponse(client, model.ProtocolType_LOGIN_REQ, "Invalid login request format")
+	ctx, done := pc.requestContext(client, baseReq)
+	if err := ctx.Err(); err != nil {
+		// 排队期间已被取消或连接已关闭，不再处理
+		done()
+		pc.sendErrorResponse(client, baseReq.RequestId, baseReq.Type, apperror.From(err, serviceErrors...))
+		return
+	}
+	req := &Request{Client: client, Base: baseReq, ctx: ctx}
+	resp, err := handler(req)
+	done()
+	var updateErr *appUpdateError
+	if errors.As(err, &updateErr) {
+		pc.sendAppUpdateResponse(client, baseReq.RequestId, baseReq.Type, updateErr.info)
 		return
 	}
-
-	// 调用业务服务处理登录
-	loginResp, err := pc.userService.Login(loginReq.JsCode)
 	if err != nil {
-		log.Errorf("Login failed: %v", err)
-		pc.sendErrorResponse(client, model.ProtocolType_LOGIN_REQ, err.Error())
+		pc.sendErrorResponse(client, baseReq.RequestId, baseReq.Type, apperror.From(err, serviceErrors...))
 		return
 	}
+	pc.sendSuccessResponse(client, baseReq.RequestId, respType, resp)
+	for _, fn := range req.afterResponse {
+		fn()
+	}
+}
 
-	// 发送成功响应
-	pc.sendSuccessResponse(client, model.ProtocolType_LOGIN_RESP, loginResp)
+// parallelSafeProtocols 可并行处理的协议类型
+// 这些请求不依赖同一客户端前后请求的处理顺序，例如只读查询
+var parallelSafeProtocols = map[model.ProtocolType]bool{
+	model.ProtocolType_HEARTBEAT_REQ: true,
+	model.ProtocolType_CANCEL_REQ:    true,
 }
 
-// sendSuccessResponse 发送成功响应
-func (pc *ProtocolController) sendSuccessResponse(client wshub.IClient, protocolType model.ProtocolType, data proto.Message) {
-	// 序列化响应数据
-	dataBytes, err := proto.Marshal(data)
+// ClassifyMessage 获取消息的协议类型名称，供按协议类型限流使用
+func ClassifyMessage(msg []byte) string {
+	return protocolTypeOf(msg).String()
+}
+
+// IsParallelSafe 判断消息是否可以不按客户端内顺序并行处理
+func IsParallelSafe(msg []byte) bool {
+	return parallelSafeProtocols[protocolTypeOf(msg)]
+}
+
+// IsActivity 判断消息是否计入连接的空闲检测，心跳只用于保活，不计入
+func IsActivity(msg []byte) bool {
+	return protocolTypeOf(msg) != model.ProtocolType_HEARTBEAT_REQ
+}
+
+// protocolTypeOf 获取消息的协议类型
+func protocolTypeOf(msg []byte) model.ProtocolType {
+	protocolType, _ := baseFieldsOf(msg)
+	return protocolType
+}
+
+// baseFieldsOf 获取消息的协议类型和请求ID
+// 只解析 BaseRequest 的 type 和 request_id 字段，避免完整反序列化；与 proto.Unmarshal 一致，字段重复出现时以最后一个为准，
+// 消息格式错误时返回 UNKNOWN 和 0。文本编码的消息以 '{' 开头，按 JSON 解析
+func baseFieldsOf(msg []byte) (model.ProtocolType, uint64) {
+	if isJSONMessage(msg) {
+		baseReq, err := jsonCodec.decodeRequest(msg, noRequestType)
+		if err != nil {
+			return model.ProtocolType_UNKNOWN, 0
+		}
+		return baseReq.Type, baseReq.RequestId
+	}
+	protocolType := model.ProtocolType_UNKNOWN
+	var requestID uint64
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return model.ProtocolType_UNKNOWN, 0
+		}
+		msg = msg[n:]
+		if (num == 1 || num == 3) && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(msg)
+			if n < 0 {
+				return model.ProtocolType_UNKNOWN, 0
+			}
+			if num == 1 {
+				protocolType = model.ProtocolType(int32(v))
+			} else {
+				requestID = v
+			}
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return model.ProtocolType_UNKNOWN, 0
+		}
+		msg = msg[n:]
+	}
+	return protocolType, requestID
+}
+
+// HandleRateLimited 回复限流错误响应
+func (pc *ProtocolController) HandleRateLimited(client wshub.IClient, msg []byte) {
+	baseReq, err := codecOf(client).decodeRequest(msg, noRequestType)
 	if err != nil {
-		log.Errorf("Failed to marshal response data: %v", err)
+		pc.sendErrorResponse(client, baseReq.GetRequestId(), baseReq.GetType(), errTooManyRequests)
 		return
 	}
+	log.Warnf("Rate limited, user: %s, protocol type: %v", client.GetContextString(ContextKeyUserID), baseReq.Type)
+	pc.sendErrorResponse(client, baseReq.RequestId, baseReq.Type, errTooManyRequests)
+}
 
-	// 构建基础响应
-	baseResp := &model.BaseResponse{
-		Type:      protocolType,
-		Result:    model.RESP_CODE_SUCCESS,
-		Msg:       "Success",
-		Data:      dataBytes,
-		Timestamp: getCurrentTimestamp(),
+// handleLoginRequest 处理登录请求
+func (pc *ProtocolController) handleLoginRequest(r *Request, loginReq *model.LoginRequest) (*model.LoginResponse, error) {
+	client := r.Client
+
+	// 检查客户端版本，并记录版本信息供之后的请求检查
+	appUpdate := pc.versionService.Check(loginReq.Platform, loginReq.EnvVersion, loginReq.Version)
+	if appUpdate.GetForce() {
+		return nil, &appUpdateError{info: appUpdate}
 	}
+	client.SetContextValue(ContextKeyPlatform, loginReq.Platform)
+	client.SetContextValue(ContextKeyEnvVersion, loginReq.EnvVersion)
+	client.SetContextValue(ContextKeyVersion, int(loginReq.Version))
 
-	// 序列化基础响应
-	respBytes, err := proto.Marshal(baseResp)
+	// 调用业务服务处理登录
+	loginResp, err := pc.userService.Login(r.Context(), loginReq.JsCode)
 	if err != nil {
-		log.Errorf("Failed to marshal base response: %v", err)
-		return
+		log.Errorf("Login failed: %v", err)
+		return nil, err
 	}
 
-	// 发送响应
-	if err := client.SendBinary(respBytes); err != nil {
-		log.Errorf("Failed to send response: %v", err)
+	// 签发会话令牌，同一连接重复登录时注销旧令牌
+	labID := loginResp.GetLabInfo().GetLab().GetId()
+	permissions := loginResp.GetLabInfo().GetUserRole().GetPermissionFlags()
+	session, err := pc.sessionService.Create(loginResp.User.GetId(), labID, permissions, service.ClientVersion{
+		Platform:   loginReq.Platform,
+		EnvVersion: loginReq.EnvVersion,
+		Version:    loginReq.Version,
+	})
+	if err != nil {
+		return nil, apperror.Wrap(err, model.ErrorCode_INTERNAL, "Create session failed")
+	}
+	if oldToken := client.GetContextString(ContextKeySessionToken); oldToken != "" {
+		pc.sessionService.Revoke(oldToken)
 	}
+	loginResp.SessionToken = session.Token
+	loginResp.SessionExpireAt = session.ExpireAt.Unix()
+	loginResp.AppUpdate = appUpdate
+
+	// 记录登录状态，并加入所在实验室的推送主题
+	client.SetContextValue(ContextKeyUserID, loginResp.User.GetId())
+	client.SetContextValue(ContextKeySessionToken, session.Token)
+	client.SetContextValue(ContextKeyPermissions, permissions)
+	if oldLabID := client.GetContextString(ContextKeyLabID); oldLabID != "" {
+		pc.hub.Leave(client, LabTopic(oldLabID))
+	}
+	if labID != "" {
+		client.SetContextValue(ContextKeyLabID, labID)
+		pc.hub.Join(client, LabTopic(labID))
+	}
+
+	// 登录响应发送后投递离线期间未确认的推送，推送排在登录响应之后
+	userID := loginResp.User.GetId()
+	r.AfterResponse(func() {
+		go pc.deliverPendingPushes(connContext(client), userID)
+	})
+	return loginResp, nil
+}
+
+// sendSuccessResponse 发送成功响应，requestID 为对应请求的请求ID
+func (pc *ProtocolController) sendSuccessResponse(client wshub.IClient, requestID uint64, protocolType model.ProtocolType, data proto.Message) {
+	pc.send(client, &model.BaseResponse{
+		Type:      protocolType,
+		Result:    model.RESP_CODE_SUCCESS,
+		Msg:       "Success",
+		Timestamp: getCurrentTimestamp(),
+		RequestId: requestID,
+	}, data)
 }
 
-// sendErrorResponse 发送错误响应
-func (pc *ProtocolController) sendErrorResponse(client wshub.IClient, protocolType model.ProtocolType, errorMsg string) {
-	baseResp := &model.BaseResponse{
+// sendErrorResponse 发送错误响应，requestID 为对应请求的请求ID，无法解析请求时为 0
+// 客户端只收到错误码、提示消息和详情，内部原因不会返回
+func (pc *ProtocolController) sendErrorResponse(client wshub.IClient, requestID uint64, protocolType model.ProtocolType, appErr *apperror.Error) {
+	pc.send(client, &model.BaseResponse{
 		Type:      protocolType,
 		Result:    model.RESP_CODE_ERROR,
-		Msg:       errorMsg,
-		Data:      nil,
+		Msg:       appErr.Message,
 		Timestamp: getCurrentTimestamp(),
-	}
+		RequestId: requestID,
+		Code:      appErr.Code,
+		Details:   appErr.Details,
+	}, nil)
+}
+
+// sendAppUpdateResponse 发送版本过低响应，请求未被处理，data 为升级信息
+func (pc *ProtocolController) sendAppUpdateResponse(client wshub.IClient, requestID uint64, protocolType model.ProtocolType, info *model.AppUpdateInfo) {
+	pc.send(client, &model.BaseResponse{
+		Type:      protocolType,
+		Result:    model.RESP_CODE_APP_UPDATE,
+		Msg:       info.Message,
+		Timestamp: getCurrentTimestamp(),
+		RequestId: requestID,
+		Code:      model.ErrorCode_APP_UPDATE_REQUIRED,
+	}, info)
+}
 
-	respBytes, err := proto.Marshal(baseResp)
+// send 按客户端协商的编码发送响应，data 序列化后作为 baseResp 的数据，为 nil 时不携带数据
+func (pc *ProtocolController) send(client wshub.IClient, baseResp *model.BaseResponse, data proto.Message) {
+	if data != nil {
+		dataBytes, err := proto.Marshal(data)
+		if err != nil {
+			log.Errorf("Failed to marshal response data: %v", err)
+			return
+		}
+		baseResp.Data = dataBytes
+	}
+	msg, err := codecOf(client).encodeResponse(baseResp, data)
 	if err != nil {
-		log.Errorf("Failed to marshal error response: %v", err)
+		log.Errorf("Failed to marshal %s response: %v", baseResp.Type, err)
 		return
 	}
+	if err := wshub.SendMessage(client, msg); err != nil {
+		log.Errorf("Failed to send %s response: %v", baseResp.Type, err)
+	}
+}
 
-	if err := client.SendBinary(respBytes); err != nil {
-		log.Errorf("Failed to send error response: %v", err)
+// NewPushMessage 构建服务端推送消息，推送标记为 unsolicited 且不携带请求ID，
+// 客户端据此与请求的响应区分开，可通过 Hub 的 Publish / SendTo / Broadcast 发送
+// 消息为 protobuf 二进制编码，需要发送给使用 JSON 子协议的客户端时使用 NewJSONPushMessage
+func NewPushMessage(protocolType model.ProtocolType, data proto.Message) (*wshub.Message, error) {
+	return newPushMessage(protobufCodec, protocolType, data)
+}
+
+// NewJSONPushMessage 构建 protojson 文本编码的服务端推送消息，用于使用 JSON 子协议的客户端
+func NewJSONPushMessage(protocolType model.ProtocolType, data proto.Message) (*wshub.Message, error) {
+	return newPushMessage(jsonCodec, protocolType, data)
+}
+
+// newPushMessage 按指定编码构建服务端推送消息
+func newPushMessage(c codec, protocolType model.ProtocolType, data proto.Message) (*wshub.Message, error) {
+	dataBytes, err := proto.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal push data: %w", err)
+	}
+	msg, err := c.encodeResponse(&model.BaseResponse{
+		Type:        protocolType,
+		Result:      model.RESP_CODE_SUCCESS,
+		Data:        dataBytes,
+		Timestamp:   getCurrentTimestamp(),
+		Unsolicited: true,
+	}, data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal push message: %w", err)
 	}
+	return msg, nil
 }
 
 // getCurrentTimestamp 获取当前时间戳
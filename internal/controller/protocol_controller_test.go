@@ -0,0 +1,684 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"happyAssistant/internal/config"
+	"happyAssistant/internal/model"
+	"happyAssistant/internal/service"
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wshubtest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// appendType 追加一个 BaseRequest.type 字段
+func appendType(b []byte, protocolType model.ProtocolType) []byte {
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(protocolType))
+}
+
+// appendRequestID 追加一个 BaseRequest.request_id 字段
+func appendRequestID(b []byte, requestID uint64) []byte {
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	return protowire.AppendVarint(b, requestID)
+}
+
+func TestProtocolTypeOf(t *testing.T) {
+	login := model.ProtocolType_LOGIN_REQ
+	heartbeat := model.ProtocolType_HEARTBEAT_REQ
+	tests := []struct {
+		name         string
+		msg          []byte
+		want         model.ProtocolType
+		wantParallel bool
+	}{
+		{name: "empty", msg: nil, want: model.ProtocolType_UNKNOWN},
+		{name: "single type", msg: appendType(nil, heartbeat), want: heartbeat, wantParallel: true},
+		{name: "type after other fields", msg: appendType(appendRequestID(nil, 7), login), want: login},
+		{name: "repeated type takes last", msg: appendType(appendType(nil, login), heartbeat), want: heartbeat, wantParallel: true},
+		{name: "parallel type overridden by later type", msg: appendType(appendRequestID(appendType(nil, heartbeat), 7), login), want: login},
+		{name: "truncated after type", msg: appendType(nil, heartbeat)[:1], want: model.ProtocolType_UNKNOWN},
+		{name: "malformed after type", msg: append(appendType(nil, heartbeat), 0xff), want: model.ProtocolType_UNKNOWN},
+		{name: "json", msg: []byte(` {"type":"HEARTBEAT_REQ","requestId":"1"}`), want: heartbeat, wantParallel: true},
+		{name: "invalid json", msg: []byte(`{"type":`), want: model.ProtocolType_UNKNOWN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := protocolTypeOf(tt.msg); got != tt.want {
+				t.Errorf("protocolTypeOf() = %v, want %v", got, tt.want)
+			}
+			if got := IsParallelSafe(tt.msg); got != tt.wantParallel {
+				t.Errorf("IsParallelSafe() = %v, want %v", got, tt.wantParallel)
+			}
+			if isJSONMessage(tt.msg) {
+				return
+			}
+			// 与完整反序列化的结果保持一致，避免按类型分流和限流与实际处理的协议不同
+			var baseReq model.BaseRequest
+			if err := proto.Unmarshal(tt.msg, &baseReq); err == nil && baseReq.Type != tt.want {
+				t.Errorf("proto.Unmarshal type = %v, protocolTypeOf = %v", baseReq.Type, tt.want)
+			}
+		})
+	}
+}
+
+// newTestController 创建不依赖数据库的协议控制器，只注册不访问数据库的处理器所需的服务
+func newTestController(t *testing.T) *ProtocolController {
+	t.Helper()
+	pc := &ProtocolController{
+		sessionService: service.NewSessionService(time.Hour),
+		versionService: service.NewVersionService(config.VersionConfig{}),
+	}
+	pc.registerHandlers()
+	return pc
+}
+
+// newTestClient 创建经过 HandleOpen 初始化的内存客户端，subprotocol 为空时使用 protobuf 编码
+func newTestClient(pc *ProtocolController, subprotocol string) *wshubtest.Client {
+	client := wshubtest.NewClient()
+	pc.HandleOpen(client)
+	if subprotocol != "" {
+		client.SetContextValue(ContextKeySubprotocol, subprotocol)
+	}
+	return client
+}
+
+// encodeRequest 按 protobuf 编码构建基础请求
+func encodeRequest(t *testing.T, protocolType model.ProtocolType, requestID uint64, data proto.Message) []byte {
+	t.Helper()
+	baseReq := &model.BaseRequest{Type: protocolType, RequestId: requestID}
+	if data != nil {
+		dataBytes, err := proto.Marshal(data)
+		if err != nil {
+			t.Fatalf("marshal request data: %v", err)
+		}
+		baseReq.Data = dataBytes
+	}
+	msg, err := proto.Marshal(baseReq)
+	if err != nil {
+		t.Fatalf("marshal base request: %v", err)
+	}
+	return msg
+}
+
+// decodeResponse 解码客户端收到的基础响应，文本帧的内嵌 data 解析到 data 中，二进制帧从 BaseResponse.Data 解析
+func decodeResponse(t *testing.T, frame *wshub.Message, data proto.Message) *model.BaseResponse {
+	t.Helper()
+	var resp model.BaseResponse
+	if frame.Type == websocket.TextMessage {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(frame.Data, &fields); err != nil {
+			t.Fatalf("unmarshal text frame: %v", err)
+		}
+		embedded := fields["data"]
+		delete(fields, "data")
+		envelope, _ := json.Marshal(fields)
+		if err := protojson.Unmarshal(envelope, &resp); err != nil {
+			t.Fatalf("unmarshal text response: %v", err)
+		}
+		if data != nil && len(embedded) > 0 {
+			if err := protojson.Unmarshal(embedded, data); err != nil {
+				t.Fatalf("unmarshal text response data: %v", err)
+			}
+		}
+		return &resp
+	}
+	if err := proto.Unmarshal(frame.Data, &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if data != nil {
+		if err := proto.Unmarshal(resp.Data, data); err != nil {
+			t.Fatalf("unmarshal response data: %v", err)
+		}
+	}
+	return &resp
+}
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		subprotocol   string
+		msg           func(t *testing.T) []byte
+		wantType      model.ProtocolType
+		wantResult    model.RESP_CODE
+		wantRequestID uint64
+		wantCode      model.ErrorCode
+		wantFrame     int
+	}{
+		{
+			name: "heartbeat",
+			msg: func(t *testing.T) []byte {
+				return encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 5, &model.HeartbeatRequest{ClientTime: 42})
+			},
+			wantType: model.ProtocolType_HEARTBEAT_RESP, wantResult: model.RESP_CODE_SUCCESS, wantRequestID: 5,
+			wantFrame: websocket.BinaryMessage,
+		},
+		{
+			name:        "heartbeat over json",
+			subprotocol: SubprotocolJSON,
+			msg: func(*testing.T) []byte {
+				return []byte(`{"type":"HEARTBEAT_REQ","requestId":"6","data":{"clientTime":"42"}}`)
+			},
+			wantType: model.ProtocolType_HEARTBEAT_RESP, wantResult: model.RESP_CODE_SUCCESS, wantRequestID: 6,
+			wantFrame: websocket.TextMessage,
+		},
+		{
+			name: "unknown protocol",
+			msg: func(t *testing.T) []byte {
+				return encodeRequest(t, model.ProtocolType_PUSH, 7, nil)
+			},
+			wantType: model.ProtocolType_PUSH, wantResult: model.RESP_CODE_ERROR, wantRequestID: 7,
+			wantCode: model.ErrorCode_UNKNOWN_PROTOCOL, wantFrame: websocket.BinaryMessage,
+		},
+		{
+			name: "login required",
+			msg: func(t *testing.T) []byte {
+				return encodeRequest(t, model.ProtocolType_TRANSFER_BEGIN_REQ, 8, &model.TransferBeginRequest{})
+			},
+			wantType: model.ProtocolType_TRANSFER_BEGIN_REQ, wantResult: model.RESP_CODE_ERROR, wantRequestID: 8,
+			wantCode: model.ErrorCode_NOT_LOGGED_IN, wantFrame: websocket.BinaryMessage,
+		},
+		{
+			name: "invalid request data",
+			msg: func(t *testing.T) []byte {
+				msg, _ := proto.Marshal(&model.BaseRequest{Type: model.ProtocolType_HEARTBEAT_REQ, RequestId: 9, Data: []byte{0xff}})
+				return msg
+			},
+			wantType: model.ProtocolType_HEARTBEAT_REQ, wantResult: model.RESP_CODE_ERROR, wantRequestID: 9,
+			wantCode: model.ErrorCode_INVALID_ARGUMENT, wantFrame: websocket.BinaryMessage,
+		},
+		{
+			name:     "malformed message",
+			msg:      func(*testing.T) []byte { return []byte{0xff, 0xff} },
+			wantType: model.ProtocolType_UNKNOWN, wantResult: model.RESP_CODE_ERROR,
+			wantCode: model.ErrorCode_INVALID_ARGUMENT, wantFrame: websocket.BinaryMessage,
+		},
+		{
+			name:        "invalid json data keeps request id",
+			subprotocol: SubprotocolJSON,
+			msg: func(*testing.T) []byte {
+				return []byte(`{"type":"HEARTBEAT_REQ","requestId":"10","data":{"clientTime":"x"}}`)
+			},
+			wantType: model.ProtocolType_HEARTBEAT_REQ, wantResult: model.RESP_CODE_ERROR, wantRequestID: 10,
+			wantCode: model.ErrorCode_INVALID_ARGUMENT, wantFrame: websocket.TextMessage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newTestController(t)
+			client := newTestClient(pc, tt.subprotocol)
+
+			pc.HandleMessage(client, tt.msg(t))
+
+			frames := client.Frames()
+			if len(frames) != 1 {
+				t.Fatalf("got %d frames, want 1", len(frames))
+			}
+			if frames[0].Type != tt.wantFrame {
+				t.Errorf("frame type = %d, want %d", frames[0].Type, tt.wantFrame)
+			}
+			resp := decodeResponse(t, frames[0], nil)
+			if resp.Type != tt.wantType || resp.Result != tt.wantResult || resp.RequestId != tt.wantRequestID {
+				t.Errorf("response = (%v, %v, %d), want (%v, %v, %d)",
+					resp.Type, resp.Result, resp.RequestId, tt.wantType, tt.wantResult, tt.wantRequestID)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %v, want %v", resp.Code, tt.wantCode)
+			}
+			if resp.Unsolicited {
+				t.Error("response marked unsolicited")
+			}
+		})
+	}
+}
+
+func TestHandleMessageHeartbeatData(t *testing.T) {
+	for _, subprotocol := range []string{SubprotocolProtobuf, SubprotocolJSON} {
+		t.Run(subprotocol, func(t *testing.T) {
+			pc := newTestController(t)
+			pc.SetHeartbeatCounter("unread", func(wshub.IClient) int32 { return 3 })
+			pc.SetHeartbeatCounter("zero", func(wshub.IClient) int32 { return 0 })
+			client := newTestClient(pc, subprotocol)
+
+			msg := encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 1, &model.HeartbeatRequest{ClientTime: 42})
+			if subprotocol == SubprotocolJSON {
+				msg = []byte(`{"type":"HEARTBEAT_REQ","requestId":"1","data":{"clientTime":"42"}}`)
+			}
+			pc.HandleMessage(client, msg)
+
+			var heartbeat model.HeartbeatResponse
+			decodeResponse(t, client.LastFrame(), &heartbeat)
+			if heartbeat.ClientTime != 42 || heartbeat.ServerTime == 0 {
+				t.Errorf("heartbeat = %+v, want client time 42 and server time set", &heartbeat)
+			}
+			if len(heartbeat.Counters) != 1 || heartbeat.Counters["unread"] != 3 {
+				t.Errorf("counters = %v, want only unread=3", heartbeat.Counters)
+			}
+		})
+	}
+}
+
+func TestHandleMessageOverServer(t *testing.T) {
+	tests := []struct {
+		name        string
+		subprotocol string
+		wantFrame   int
+	}{
+		{name: "protobuf", subprotocol: SubprotocolProtobuf, wantFrame: websocket.BinaryMessage},
+		{name: "json", subprotocol: SubprotocolJSON, wantFrame: websocket.TextMessage},
+		{name: "no subprotocol", wantFrame: websocket.BinaryMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub(wshub.WithSubprotocols(Subprotocols...))
+			if err != nil {
+				t.Fatalf("new hub: %v", err)
+			}
+			pc := newTestController(t)
+			pc.hub = hub
+			hub.OnOpen = pc.HandleOpen
+			hub.OnMessage = pc.HandleMessage
+			hub.OnClose = func(client wshub.IClient, _ wshub.CloseCause) { pc.HandleClose(client) }
+			server := wshubtest.NewServer(hub)
+			defer server.Close()
+
+			header := http.Header{}
+			if tt.subprotocol != "" {
+				header.Set("Sec-WebSocket-Protocol", tt.subprotocol)
+			}
+			conn, _, err := server.Dial("", header)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			if _, err := server.WaitOpen(time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			msg := encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 3, &model.HeartbeatRequest{ClientTime: 42})
+			messageType := websocket.BinaryMessage
+			if tt.subprotocol == SubprotocolJSON {
+				msg = []byte(`{"type":"HEARTBEAT_REQ","requestId":"3","data":{"clientTime":"42"}}`)
+				messageType = websocket.TextMessage
+			}
+			if err := conn.WriteMessage(messageType, msg); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			frameType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if frameType != tt.wantFrame {
+				t.Errorf("frame type = %d, want %d", frameType, tt.wantFrame)
+			}
+			var heartbeat model.HeartbeatResponse
+			resp := decodeResponse(t, &wshub.Message{Type: frameType, Data: data}, &heartbeat)
+			if resp.Type != model.ProtocolType_HEARTBEAT_RESP || resp.Result != model.RESP_CODE_SUCCESS || resp.RequestId != 3 {
+				t.Errorf("response = (%v, %v, %d), want (HEARTBEAT_RESP, SUCCESS, 3)", resp.Type, resp.Result, resp.RequestId)
+			}
+			if heartbeat.ClientTime != 42 {
+				t.Errorf("client time = %d, want 42", heartbeat.ClientTime)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRestoresVersion(t *testing.T) {
+	pc := newTestController(t)
+	session, err := pc.sessionService.Create("u1", "lab1", 0, service.ClientVersion{Platform: "ios", EnvVersion: "release", Version: 110})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	tests := []struct {
+		name         string
+		query        string
+		wantPlatform string
+		wantVersion  int
+	}{
+		{name: "without session", query: "", wantPlatform: "", wantVersion: 0},
+		{name: "session version", query: "token=" + session.Token, wantPlatform: "ios", wantVersion: 110},
+		{name: "query overrides session", query: "token=" + session.Token + "&platform=android&env_version=release&version=120",
+			wantPlatform: "android", wantVersion: 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := pc.Authenticate(httptest.NewRequest(http.MethodGet, "/wss?"+tt.query, nil))
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			platform, _ := values[ContextKeyPlatform].(string)
+			version, _ := values[ContextKeyVersion].(int)
+			if platform != tt.wantPlatform || version != tt.wantVersion {
+				t.Errorf("platform, version = %q, %d, want %q, %d", platform, version, tt.wantPlatform, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestAuthenticateSessionToken(t *testing.T) {
+	pc := newTestController(t)
+	session, err := pc.sessionService.Create("u1", "lab1", 0, service.ClientVersion{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	revoked, err := pc.sessionService.Create("u2", "lab1", 0, service.ClientVersion{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	pc.sessionService.Revoke(revoked.Token)
+
+	expiring := service.NewSessionService(time.Millisecond)
+	expired, err := expiring.Create("u3", "lab1", 0, service.ClientVersion{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	tests := []struct {
+		name          string
+		sessions      *service.SessionService
+		query         string
+		authorization string
+		wantUserID    string // 为空表示按未登录处理
+	}{
+		{name: "bearer header", authorization: "Bearer " + session.Token, wantUserID: "u1"},
+		{name: "query token", query: "token=" + session.Token, wantUserID: "u1"},
+		{name: "header preferred over query", query: "token=" + revoked.Token, authorization: "Bearer " + session.Token, wantUserID: "u1"},
+		{name: "header without bearer scheme", authorization: session.Token},
+		{name: "other scheme", authorization: "Basic " + session.Token},
+		{name: "invalid token", authorization: "Bearer invalid"},
+		{name: "revoked token", authorization: "Bearer " + revoked.Token},
+		{name: "expired token", sessions: expiring, authorization: "Bearer " + expired.Token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := pc
+			if tt.sessions != nil {
+				tc = newTestController(t)
+				tc.sessionService = tt.sessions
+			}
+			r := httptest.NewRequest(http.MethodGet, "/wss?"+tt.query, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			// 令牌无效时不拒绝握手，连接按未登录处理
+			values, err := tc.Authenticate(r)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			userID, _ := values[ContextKeyUserID].(string)
+			if userID != tt.wantUserID {
+				t.Errorf("user id = %q, want %q", userID, tt.wantUserID)
+			}
+		})
+	}
+}
+
+func TestHandleMessageVersionCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		protocolType model.ProtocolType
+		data         proto.Message
+		want         model.RESP_CODE
+	}{
+		{name: "heartbeat skips version check", protocolType: model.ProtocolType_HEARTBEAT_REQ,
+			data: &model.HeartbeatRequest{}, want: model.RESP_CODE_SUCCESS},
+		{name: "missing version requires update", protocolType: model.ProtocolType_TRANSFER_BEGIN_REQ,
+			data: &model.TransferBeginRequest{}, want: model.RESP_CODE_APP_UPDATE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newTestController(t)
+			pc.versionService = service.NewVersionService(config.VersionConfig{Rules: []config.VersionRule{{MinVersion: 100}}})
+			pc.registerHandlers()
+			client := newTestClient(pc, "")
+
+			pc.HandleMessage(client, encodeRequest(t, tt.protocolType, 1, tt.data))
+			if resp := decodeResponse(t, client.LastFrame(), nil); resp.Result != tt.want {
+				t.Errorf("result = %v, want %v", resp.Result, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsActivity(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+		want bool
+	}{
+		{name: "heartbeat", msg: appendType(nil, model.ProtocolType_HEARTBEAT_REQ)},
+		{name: "json heartbeat", msg: []byte(`{"type":"HEARTBEAT_REQ"}`)},
+		{name: "business request", msg: appendType(nil, model.ProtocolType_TRANSFER_BEGIN_REQ), want: true},
+		{name: "malformed", msg: []byte{0xff}, want: true},
+	}
+	for _, tt := range tests {
+		if got := IsActivity(tt.msg); got != tt.want {
+			t.Errorf("%s: IsActivity() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// cancelRequest 发送 CANCEL_REQ 并返回响应中的 canceled
+func cancelRequest(t *testing.T, pc *ProtocolController, client *wshubtest.Client, requestID, target uint64) bool {
+	t.Helper()
+	pc.HandleMessage(client, encodeRequest(t, model.ProtocolType_CANCEL_REQ, requestID, &model.CancelRequest{RequestId: target}))
+	var resp model.CancelResponse
+	if base := decodeResponse(t, client.LastFrame(), &resp); base.Result != model.RESP_CODE_SUCCESS {
+		t.Fatalf("cancel result = %v, want SUCCESS", base.Result)
+	}
+	return resp.Canceled
+}
+
+func TestCancelRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T, pc *ProtocolController, client *wshubtest.Client) // 取消前的操作
+		target   uint64
+		want     []bool          // 依次发送的 CANCEL_REQ 的 canceled
+		wantCode model.ErrorCode // 目标请求之后开始处理时的错误码，NO_ERROR 表示不再处理目标请求
+	}{
+		{
+			name: "queued request",
+			prepare: func(t *testing.T, pc *ProtocolController, client *wshubtest.Client) {
+				pc.HandleReceive(client, encodeRequest(t, model.ProtocolType_TRANSFER_BEGIN_REQ, 5, &model.TransferBeginRequest{}))
+			},
+			target: 5, want: []bool{true, false}, wantCode: model.ErrorCode_CANCELED,
+		},
+		{
+			name: "completed request",
+			prepare: func(t *testing.T, pc *ProtocolController, client *wshubtest.Client) {
+				msg := encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 5, &model.HeartbeatRequest{})
+				pc.HandleReceive(client, msg)
+				pc.HandleMessage(client, msg)
+			},
+			target: 5, want: []bool{false},
+		},
+		{name: "unknown request", target: 5, want: []bool{false}},
+		{name: "self", target: 100, want: []bool{false}},
+		{name: "zero request id", target: 0, want: []bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newTestController(t)
+			client := newTestClient(pc, "")
+			if tt.prepare != nil {
+				tt.prepare(t, pc, client)
+			}
+			for i, want := range tt.want {
+				if got := cancelRequest(t, pc, client, uint64(100+i), tt.target); got != want {
+					t.Errorf("cancel %d canceled = %v, want %v", i, got, want)
+				}
+			}
+			if tt.wantCode == model.ErrorCode_NO_ERROR {
+				return
+			}
+			pc.HandleMessage(client, encodeRequest(t, model.ProtocolType_TRANSFER_BEGIN_REQ, tt.target, &model.TransferBeginRequest{}))
+			resp := decodeResponse(t, client.LastFrame(), nil)
+			if resp.GetRequestId() != tt.target || resp.GetCode() != tt.wantCode {
+				t.Errorf("target response id, code = %d, %v, want %d, %v", resp.GetRequestId(), resp.GetCode(), tt.target, tt.wantCode)
+			}
+			if len(requestsOf(client).inflight) != 0 {
+				t.Errorf("inflight = %d, want 0", len(requestsOf(client).inflight))
+			}
+		})
+	}
+}
+
+func TestCancelInflightRequest(t *testing.T) {
+	pc := newTestController(t)
+	started := make(chan struct{})
+	pc.handlers = NewHandlerRegistry()
+	Register(pc.handlers, model.ProtocolType_CANCEL_REQ, pc.handleCancelRequest)
+	Register(pc.handlers, model.ProtocolType_PUSH_ACK_REQ, func(r *Request, _ *model.PushAckRequest) (*model.PushAckResponse, error) {
+		close(started)
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+	client := newTestClient(pc, "")
+
+	msg := encodeRequest(t, model.ProtocolType_PUSH_ACK_REQ, 5, &model.PushAckRequest{})
+	pc.HandleReceive(client, msg)
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		pc.HandleMessage(client, msg)
+	}()
+	<-started
+
+	if !cancelRequest(t, pc, client, 6, 5) {
+		t.Error("canceled = false, want true")
+	}
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("request not canceled")
+	}
+	frames, err := client.WaitFrames(2, time.Second)
+	if err != nil {
+		t.Fatalf("WaitFrames: %v", err)
+	}
+	for _, frame := range frames {
+		if resp := decodeResponse(t, frame, nil); resp.GetRequestId() == 5 && resp.GetCode() != model.ErrorCode_CANCELED {
+			t.Errorf("request code = %v, want CANCELED", resp.GetCode())
+		}
+	}
+}
+
+func TestAfterResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantFrames []string // 按发送顺序，response 表示处理器的响应
+	}{
+		{name: "after success response", wantFrames: []string{"response", "after"}},
+		{name: "skipped on error", err: errInvalidRequest, wantFrames: []string{"response"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newTestController(t)
+			pc.handlers = NewHandlerRegistry()
+			Register(pc.handlers, model.ProtocolType_HEARTBEAT_REQ, func(r *Request, _ *model.HeartbeatRequest) (*model.HeartbeatResponse, error) {
+				r.AfterResponse(func() { _ = r.Client.SendText([]byte("after")) })
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &model.HeartbeatResponse{}, nil
+			})
+			client := newTestClient(pc, "")
+
+			pc.HandleMessage(client, encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 1, &model.HeartbeatRequest{}))
+			var got []string
+			for _, frame := range client.Frames() {
+				if frame.Type == websocket.TextMessage {
+					got = append(got, string(frame.Data))
+				} else {
+					got = append(got, "response")
+				}
+			}
+			if len(got) != len(tt.wantFrames) {
+				t.Fatalf("frames = %v, want %v", got, tt.wantFrames)
+			}
+			for i := range got {
+				if got[i] != tt.wantFrames[i] {
+					t.Errorf("frames = %v, want %v", got, tt.wantFrames)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestHandleDispatchError(t *testing.T) {
+	pc := newTestController(t)
+	client := newTestClient(pc, "")
+	msg := encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 7, &model.HeartbeatRequest{})
+	pc.HandleReceive(client, msg)
+
+	pc.HandleDispatchError(client, msg, errors.New("dispatcher is stopped"))
+	if n := len(requestsOf(client).inflight); n != 0 {
+		t.Errorf("inflight = %d, want 0", n)
+	}
+	resp := decodeResponse(t, client.LastFrame(), nil)
+	if resp.GetRequestId() != 7 || resp.GetCode() != model.ErrorCode_UNAVAILABLE {
+		t.Errorf("response = (%d, %v), want (7, UNAVAILABLE)", resp.GetRequestId(), resp.GetCode())
+	}
+	// 之后无法再取消
+	if cancelRequest(t, pc, client, 8, 7) {
+		t.Error("canceled = true, want false")
+	}
+}
+
+func TestRequestTimeoutIncludesQueueTime(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	tests := []struct {
+		name        string
+		queued      time.Duration // 收到请求到开始处理之间的排队时间
+		wantHandled bool
+		wantCode    model.ErrorCode
+	}{
+		{name: "within timeout", queued: 0, wantHandled: true},
+		{name: "expired while queued", queued: 2 * timeout, wantCode: model.ErrorCode_UNAVAILABLE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newTestController(t)
+			pc.requestConfig = config.RequestConfig{Timeout: timeout}
+			var handled bool
+			var deadline time.Time
+			pc.handlers = NewHandlerRegistry()
+			Register(pc.handlers, model.ProtocolType_HEARTBEAT_REQ, func(r *Request, _ *model.HeartbeatRequest) (*model.HeartbeatResponse, error) {
+				handled = true
+				deadline, _ = r.Context().Deadline()
+				return &model.HeartbeatResponse{}, nil
+			})
+			client := newTestClient(pc, "")
+
+			msg := encodeRequest(t, model.ProtocolType_HEARTBEAT_REQ, 1, &model.HeartbeatRequest{})
+			received := time.Now()
+			pc.HandleReceive(client, msg)
+			time.Sleep(tt.queued)
+			pc.HandleMessage(client, msg)
+
+			if handled != tt.wantHandled {
+				t.Fatalf("handled = %v, want %v", handled, tt.wantHandled)
+			}
+			if handled && (deadline.Before(received) || deadline.After(received.Add(timeout+10*time.Millisecond))) {
+				t.Errorf("deadline = %v after receipt, want about %v", deadline.Sub(received), timeout)
+			}
+			if resp := decodeResponse(t, client.LastFrame(), nil); resp.GetCode() != tt.wantCode {
+				t.Errorf("code = %v, want %v", resp.GetCode(), tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+// Push 向用户发送需要确认的推送，kind 为推送类型，客户端据此解析 data
+// 用户不在线时推送会保存，下次登录后投递，ctx 只用于保存推送
+func (pc *ProtocolController) Push(ctx context.Context, userID, kind string, data proto.Message) (string, error) {
+	dataBytes, err := proto.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("marshal push data: %w", err)
+	}
+	msg, err := pc.pushService.Push(ctx, userID, kind, dataBytes)
+	if err != nil {
+		return "", err
+	}
+	return msg.Id, nil
+}
+
+// deliverPush 将推送发送给用户的所有在线连接，按连接协商的子协议分别编码
+func (pc *ProtocolController) deliverPush(msg *model.PushMessage) bool {
+	return pc.sendPush(msg, protobufCodec)+pc.sendPush(msg, jsonCodec) > 0
+}
+
+// sendPush 将推送发送给用户使用指定编码的在线连接，返回接收的连接数
+func (pc *ProtocolController) sendPush(msg *model.PushMessage, c codec) int {
+	pushMsg, err := newPushMessage(c, model.ProtocolType_PUSH, msg)
+	if err != nil {
+		log.Errorf("Failed to build push %s: %v", msg.Id, err)
+		return 0
+	}
+	return pc.hub.SendTo(func(client wshub.IClient) bool {
+		return client.GetContextString(ContextKeyUserID) == msg.UserId && codecOf(client) == c
+	}, pushMsg)
+}
+
+// deliverPendingPushes 投递用户未确认的推送，ctx 为连接的上下文，连接关闭后不再查询
+func (pc *ProtocolController) deliverPendingPushes(ctx context.Context, userID string) {
+	if err := pc.pushService.DeliverPending(ctx, userID); err != nil {
+		log.Errorf("Deliver pending pushes failed, user: %s: %v", userID, err)
+	}
+}
+
+// handlePushAckRequest 处理推送确认请求
+func (pc *ProtocolController) handlePushAckRequest(r *Request, req *model.PushAckRequest) (*model.PushAckResponse, error) {
+	acked, err := pc.pushService.Ack(r.Context(), r.UserID(), req.Ids)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PushAckResponse{Acked: int32(acked)}, nil
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"context"
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// contextKeyRequests 客户端 Context 中保存连接请求状态的键
+const contextKeyRequests = "requests"
+
+// clientRequests 连接上排队和处理中的请求
+// 连接建立时创建，连接关闭时取消 ctx，连接上所有请求的上下文都派生自 ctx
+type clientRequests struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	inflight map[uint64]*inflightRequest
+}
+
+// inflightRequest 排队或处理中的请求
+type inflightRequest struct {
+	cancel     context.CancelFunc // 开始处理后设置，排队中为 nil
+	canceled   bool               // 排队期间已被 CANCEL_REQ 取消，开始处理时直接取消上下文
+	receivedAt time.Time          // 收到请求的时间，处理超时从此时开始计算，排队时间也计入
+}
+
+func newClientRequests() *clientRequests {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &clientRequests{
+		ctx:      ctx,
+		cancel:   cancel,
+		inflight: make(map[uint64]*inflightRequest),
+	}
+}
+
+// requestsOf 获取客户端的请求状态，未经过 HandleOpen 的客户端返回 nil
+func requestsOf(client wshub.IClient) *clientRequests {
+	requests, _ := client.GetContextValue(contextKeyRequests).(*clientRequests)
+	return requests
+}
+
+// connContext 获取连接的上下文，连接关闭后结束，用于连接范围内的异步任务（如投递未确认的推送）
+func connContext(client wshub.IClient) context.Context {
+	if requests := requestsOf(client); requests != nil {
+		return requests.ctx
+	}
+	return context.Background()
+}
+
+// requestContext 为请求创建上下文，返回的 done 需在处理完成后调用
+// 上下文在连接关闭、超过协议类型的处理超时或客户端发送 CANCEL_REQ 时取消；
+// 处理超时从收到请求时开始计算，在分发器中排队的时间也计入，排队期间已超时的请求不再处理
+func (pc *ProtocolController) requestContext(client wshub.IClient, baseReq *model.BaseRequest) (context.Context, func()) {
+	requests := requestsOf(client)
+	parent := context.Background()
+	if requests != nil {
+		parent = requests.ctx
+	}
+
+	// 沿用收到消息时登记的记录，没有请求ID的请求无法被 CANCEL_REQ 引用，不登记
+	var req *inflightRequest
+	if requests != nil && baseReq.RequestId != 0 {
+		requests.mu.Lock()
+		var ok bool
+		req, ok = requests.inflight[baseReq.RequestId]
+		if !ok || req.cancel != nil {
+			req = &inflightRequest{receivedAt: time.Now()}
+			requests.inflight[baseReq.RequestId] = req
+		}
+		requests.mu.Unlock()
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := pc.requestTimeout(baseReq.Type); timeout > 0 {
+		receivedAt := time.Now()
+		if req != nil && !req.receivedAt.IsZero() {
+			receivedAt = req.receivedAt
+		}
+		ctx, cancel = context.WithDeadline(parent, receivedAt.Add(timeout))
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	if req == nil {
+		return ctx, cancel
+	}
+
+	// 排队期间已被取消的请求不再处理
+	requests.mu.Lock()
+	req.cancel = cancel
+	canceled := req.canceled
+	requests.mu.Unlock()
+	if canceled {
+		cancel()
+	}
+	return ctx, func() {
+		requests.mu.Lock()
+		// 客户端重复使用请求ID时，只移除自己的记录
+		if requests.inflight[baseReq.RequestId] == req {
+			delete(requests.inflight, baseReq.RequestId)
+		}
+		requests.mu.Unlock()
+		cancel()
+	}
+}
+
+// HandleReceive 登记收到的请求，需设置为 Hub 的 OnReceive
+// 启用工作协程时请求可能在分发器中排队，提前登记后 CANCEL_REQ 也能取消尚未开始处理的请求
+func (pc *ProtocolController) HandleReceive(client wshub.IClient, msg []byte) {
+	requests := requestsOf(client)
+	if requests == nil {
+		return
+	}
+	protocolType, requestID := baseFieldsOf(msg)
+	if requestID == 0 || protocolType == model.ProtocolType_CANCEL_REQ {
+		return
+	}
+	requests.mu.Lock()
+	requests.inflight[requestID] = &inflightRequest{receivedAt: time.Now()}
+	requests.mu.Unlock()
+}
+
+// HandleDispatchError 处理未能放入分发器的消息，需设置为 Hub 的 OnDispatchError
+// 消息不会再被处理，移除收到时登记的请求，并回复服务不可用
+func (pc *ProtocolController) HandleDispatchError(client wshub.IClient, msg []byte, err error) {
+	protocolType, requestID := baseFieldsOf(msg)
+	log.Warnf("Failed to dispatch request, user: %s, protocol type: %v: %v",
+		client.GetContextString(ContextKeyUserID), protocolType, err)
+	forgetRequest(client, requestID)
+	pc.sendErrorResponse(client, requestID, protocolType, errUnavailable)
+}
+
+// forgetRequest 移除收到消息时登记、但不会开始处理的请求，例如请求数据无效、协议类型未注册或未能放入分发器
+func forgetRequest(client wshub.IClient, requestID uint64) {
+	requests := requestsOf(client)
+	if requests == nil || requestID == 0 {
+		return
+	}
+	requests.mu.Lock()
+	if req, ok := requests.inflight[requestID]; ok && req.cancel == nil {
+		delete(requests.inflight, requestID)
+	}
+	requests.mu.Unlock()
+}
+
+// requestTimeout 获取协议类型的处理超时，<= 0 表示不限制
+func (pc *ProtocolController) requestTimeout(protocolType model.ProtocolType) time.Duration {
+	if timeout, ok := pc.requestConfig.Protocols[protocolType.String()]; ok && timeout != 0 {
+		return timeout
+	}
+	return pc.requestConfig.Timeout
+}
+
+// HandleClose 处理连接关闭，取消连接上所有处理中的请求
+func (pc *ProtocolController) HandleClose(client wshub.IClient) {
+	if requests := requestsOf(client); requests != nil {
+		requests.cancel()
+	}
+}
+
+// handleCancelRequest 处理取消请求
+// CANCEL_REQ 可并行处理，不会排在被取消的请求之后；仍在排队的请求标记为已取消，开始处理时直接返回 CANCELED。
+// 未启用工作协程（dispatch.workers <= 0）时消息在读协程中同步处理，被取消的请求处理完之前不会读取到取消请求
+func (pc *ProtocolController) handleCancelRequest(r *Request, req *model.CancelRequest) (*model.CancelResponse, error) {
+	requests := requestsOf(r.Client)
+	if requests == nil || req.RequestId == 0 || req.RequestId == r.Base.RequestId {
+		return &model.CancelResponse{}, nil
+	}
+	requests.mu.Lock()
+	inflight, ok := requests.inflight[req.RequestId]
+	var cancel context.CancelFunc
+	switch {
+	case !ok || inflight.canceled:
+		ok = false
+	case inflight.cancel == nil:
+		// 仍在排队，保留记录，开始处理时取消
+		inflight.canceled = true
+	default:
+		cancel = inflight.cancel
+		delete(requests.inflight, req.RequestId)
+	}
+	requests.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return &model.CancelResponse{Canceled: ok}, nil
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"happyAssistant/internal/apperror"
+	"happyAssistant/internal/model"
+	"happyAssistant/internal/service"
+	"strconv"
+)
+
+// TransferService 获取分片传输服务，供引用上传文件的业务（订单附件、批量导入等）使用
+func (pc *ProtocolController) TransferService() *service.TransferService {
+	return pc.transferService
+}
+
+// handleTransferBeginRequest 处理开始传输请求，携带 transfer_id 时续传
+func (pc *ProtocolController) handleTransferBeginRequest(r *Request, req *model.TransferBeginRequest) (*model.TransferBeginResponse, error) {
+	userID := r.UserID()
+	transfer, err := pc.transferService.Begin(userID, req.TransferId, req.FileName, req.ContentType,
+		req.Purpose, req.TotalSize, req.Sha256)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TransferBeginResponse{
+		TransferId:   transfer.ID,
+		ReceivedSize: transfer.Received,
+		ChunkSize:    int32(pc.transferService.ChunkSize()),
+		ExpireAt:     transfer.ExpireAt.Unix(),
+	}, nil
+}
+
+// handleTransferChunkRequest 处理分片请求
+func (pc *ProtocolController) handleTransferChunkRequest(r *Request, req *model.TransferChunkRequest) (*model.TransferChunkResponse, error) {
+	userID := r.UserID()
+	received, err := pc.transferService.WriteChunk(userID, req.TransferId, req.Offset, req.Data, req.Crc32)
+	if err != nil {
+		// 返回已接收的字节数，客户端从该偏移重新发送
+		return nil, apperror.From(err, serviceErrors...).WithDetail("received_size", strconv.FormatInt(received, 10))
+	}
+	return &model.TransferChunkResponse{
+		TransferId:   req.TransferId,
+		ReceivedSize: received,
+	}, nil
+}
+
+// handleTransferFinishRequest 处理完成传输请求
+func (pc *ProtocolController) handleTransferFinishRequest(r *Request, req *model.TransferFinishRequest) (*model.TransferFinishResponse, error) {
+	userID := r.UserID()
+	transfer, err := pc.transferService.Finish(userID, req.TransferId)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TransferFinishResponse{
+		TransferId: transfer.ID,
+		Size:       transfer.TotalSize,
+		Sha256:     transfer.SHA256,
+	}, nil
+}
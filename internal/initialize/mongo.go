@@ -60,6 +60,14 @@ func InitMongoDBClient(config config.MongoConfig) *MongoDBClient {
 	return mongoClient
 }
 
+// CloseMongoDBClient 断开 MongoDB 连接，在服务退出时调用
+func CloseMongoDBClient(ctx context.Context) error {
+	if mongoClient == nil {
+		return nil
+	}
+	return mongoClient.Disconnect(ctx)
+}
+
 func GetMongoClient() *MongoDBClient {
 	return mongoClient
 }
@@ -34,19 +34,62 @@ const (
 	// 登录相关协议
 	ProtocolType_LOGIN_REQ  ProtocolType = 1 // 登录请求协议
 	ProtocolType_LOGIN_RESP ProtocolType = 2 // 登录响应协议
+	// 分片传输相关协议
+	ProtocolType_TRANSFER_BEGIN_REQ   ProtocolType = 10 // 开始（或续传）分片传输请求
+	ProtocolType_TRANSFER_BEGIN_RESP  ProtocolType = 11 // 开始分片传输响应
+	ProtocolType_TRANSFER_CHUNK_REQ   ProtocolType = 12 // 上传分片请求
+	ProtocolType_TRANSFER_CHUNK_RESP  ProtocolType = 13 // 上传分片响应
+	ProtocolType_TRANSFER_FINISH_REQ  ProtocolType = 14 // 完成分片传输请求
+	ProtocolType_TRANSFER_FINISH_RESP ProtocolType = 15 // 完成分片传输响应
+	// 服务端推送相关协议
+	ProtocolType_PUSH          ProtocolType = 20 // 服务端推送，BaseResponse.unsolicited 为 true，data 为 PushMessage
+	ProtocolType_PUSH_ACK_REQ  ProtocolType = 21 // 推送确认请求
+	ProtocolType_PUSH_ACK_RESP ProtocolType = 22 // 推送确认响应
+	// 心跳协议
+	ProtocolType_HEARTBEAT_REQ  ProtocolType = 30 // 应用层心跳请求
+	ProtocolType_HEARTBEAT_RESP ProtocolType = 31 // 应用层心跳响应
+	// 请求取消协议
+	ProtocolType_CANCEL_REQ  ProtocolType = 40 // 取消处理中的请求
+	ProtocolType_CANCEL_RESP ProtocolType = 41 // 取消请求响应
 )
 
 // Enum value maps for ProtocolType.
 var (
 	ProtocolType_name = map[int32]string{
-		0: "UNKNOWN",
-		1: "LOGIN_REQ",
-		2: "LOGIN_RESP",
+		0:  "UNKNOWN",
+		1:  "LOGIN_REQ",
+		2:  "LOGIN_RESP",
+		10: "TRANSFER_BEGIN_REQ",
+		11: "TRANSFER_BEGIN_RESP",
+		12: "TRANSFER_CHUNK_REQ",
+		13: "TRANSFER_CHUNK_RESP",
+		14: "TRANSFER_FINISH_REQ",
+		15: "TRANSFER_FINISH_RESP",
+		20: "PUSH",
+		21: "PUSH_ACK_REQ",
+		22: "PUSH_ACK_RESP",
+		30: "HEARTBEAT_REQ",
+		31: "HEARTBEAT_RESP",
+		40: "CANCEL_REQ",
+		41: "CANCEL_RESP",
 	}
 	ProtocolType_value = map[string]int32{
-		"UNKNOWN":    0,
-		"LOGIN_REQ":  1,
-		"LOGIN_RESP": 2,
+		"UNKNOWN":              0,
+		"LOGIN_REQ":            1,
+		"LOGIN_RESP":           2,
+		"TRANSFER_BEGIN_REQ":   10,
+		"TRANSFER_BEGIN_RESP":  11,
+		"TRANSFER_CHUNK_REQ":   12,
+		"TRANSFER_CHUNK_RESP":  13,
+		"TRANSFER_FINISH_REQ":  14,
+		"TRANSFER_FINISH_RESP": 15,
+		"PUSH":                 20,
+		"PUSH_ACK_REQ":         21,
+		"PUSH_ACK_RESP":        22,
+		"HEARTBEAT_REQ":        30,
+		"HEARTBEAT_RESP":       31,
+		"CANCEL_REQ":           40,
+		"CANCEL_RESP":          41,
 	}
 )
 
@@ -128,19 +171,175 @@ func (RESP_CODE) EnumDescriptor() ([]byte, []int) {
 	return file_protocol_proto_rawDescGZIP(), []int{1}
 }
 
+// 业务错误码枚举
+// 失败响应（result 为 ERROR）中标识具体的错误类型，客户端据此处理，msg 只用于展示
+type ErrorCode int32
+
+const (
+	ErrorCode_NO_ERROR            ErrorCode = 0  // 无错误，成功响应使用
+	ErrorCode_INTERNAL            ErrorCode = 1  // 服务端内部错误，详细原因只记录在服务端日志中
+	ErrorCode_INVALID_ARGUMENT    ErrorCode = 2  // 请求格式或参数错误
+	ErrorCode_NOT_FOUND           ErrorCode = 3  // 请求的资源不存在或已过期
+	ErrorCode_ALREADY_EXISTS      ErrorCode = 4  // 资源已存在
+	ErrorCode_NOT_LOGGED_IN       ErrorCode = 5  // 未登录或会话已失效
+	ErrorCode_PERMISSION_DENIED   ErrorCode = 6  // 没有操作权限
+	ErrorCode_RATE_LIMITED        ErrorCode = 7  // 请求过于频繁
+	ErrorCode_FAILED_PRECONDITION ErrorCode = 8  // 当前状态不允许该操作，例如分片偏移不连续
+	ErrorCode_PAYLOAD_TOO_LARGE   ErrorCode = 9  // 请求数据超过大小限制
+	ErrorCode_UNKNOWN_PROTOCOL    ErrorCode = 10 // 不支持的协议类型
+	ErrorCode_UNAVAILABLE         ErrorCode = 11 // 服务暂时不可用，例如数据库超时，可稍后重试
+	ErrorCode_APP_UPDATE_REQUIRED ErrorCode = 12 // 客户端版本低于最低支持版本，result 为 APP_UPDATE，data 为 AppUpdateInfo
+	ErrorCode_CANCELED            ErrorCode = 13 // 请求已被客户端取消（CANCEL_REQ）
+)
+
+// Enum value maps for ErrorCode.
+var (
+	ErrorCode_name = map[int32]string{
+		0:  "NO_ERROR",
+		1:  "INTERNAL",
+		2:  "INVALID_ARGUMENT",
+		3:  "NOT_FOUND",
+		4:  "ALREADY_EXISTS",
+		5:  "NOT_LOGGED_IN",
+		6:  "PERMISSION_DENIED",
+		7:  "RATE_LIMITED",
+		8:  "FAILED_PRECONDITION",
+		9:  "PAYLOAD_TOO_LARGE",
+		10: "UNKNOWN_PROTOCOL",
+		11: "UNAVAILABLE",
+		12: "APP_UPDATE_REQUIRED",
+		13: "CANCELED",
+	}
+	ErrorCode_value = map[string]int32{
+		"NO_ERROR":            0,
+		"INTERNAL":            1,
+		"INVALID_ARGUMENT":    2,
+		"NOT_FOUND":           3,
+		"ALREADY_EXISTS":      4,
+		"NOT_LOGGED_IN":       5,
+		"PERMISSION_DENIED":   6,
+		"RATE_LIMITED":        7,
+		"FAILED_PRECONDITION": 8,
+		"PAYLOAD_TOO_LARGE":   9,
+		"UNKNOWN_PROTOCOL":    10,
+		"UNAVAILABLE":         11,
+		"APP_UPDATE_REQUIRED": 12,
+		"CANCELED":            13,
+	}
+)
+
+func (x ErrorCode) Enum() *ErrorCode {
+	p := new(ErrorCode)
+	*p = x
+	return p
+}
+
+func (x ErrorCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_protocol_proto_enumTypes[2].Descriptor()
+}
+
+func (ErrorCode) Type() protoreflect.EnumType {
+	return &file_protocol_proto_enumTypes[2]
+}
+
+func (x ErrorCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorCode.Descriptor instead.
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{2}
+}
+
+// 客户端升级信息
+// 版本低于最低支持版本时随 APP_UPDATE 响应返回（force 为 true），请求不会被处理；
+// 低于最新版本时随登录响应返回（force 为 false），客户端可提示用户升级
+type AppUpdateInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Force         bool                   `protobuf:"varint,1,opt,name=force,proto3" json:"force,omitempty"`                                      // 是否必须升级后才能继续使用
+	MinVersion    int32                  `protobuf:"varint,2,opt,name=min_version,json=minVersion,proto3" json:"min_version,omitempty"`          // 最低支持版本
+	LatestVersion int32                  `protobuf:"varint,3,opt,name=latest_version,json=latestVersion,proto3" json:"latest_version,omitempty"` // 最新版本
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`                                   // 升级提示
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppUpdateInfo) Reset() {
+	*x = AppUpdateInfo{}
+	mi := &file_protocol_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppUpdateInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppUpdateInfo) ProtoMessage() {}
+
+func (x *AppUpdateInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppUpdateInfo.ProtoReflect.Descriptor instead.
+func (*AppUpdateInfo) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AppUpdateInfo) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+func (x *AppUpdateInfo) GetMinVersion() int32 {
+	if x != nil {
+		return x.MinVersion
+	}
+	return 0
+}
+
+func (x *AppUpdateInfo) GetLatestVersion() int32 {
+	if x != nil {
+		return x.LatestVersion
+	}
+	return 0
+}
+
+func (x *AppUpdateInfo) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 // 基础请求协议
 // 所有客户端请求的通用包装协议，包含协议类型和具体数据
 type BaseRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          ProtocolType           `protobuf:"varint,1,opt,name=type,proto3,enum=model.ProtocolType" json:"type,omitempty"` // 协议类型，用于标识具体的请求类型
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`                          // 具体请求数据（序列化后的具体协议）
+	Type          ProtocolType           `protobuf:"varint,1,opt,name=type,proto3,enum=model.ProtocolType" json:"type,omitempty"`    // 协议类型，用于标识具体的请求类型
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`                             // 具体请求数据（序列化后的具体协议）
+	RequestId     uint64                 `protobuf:"varint,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // 请求ID，由客户端生成（同一连接内唯一，建议递增），服务端在对应响应中原样返回
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BaseRequest) Reset() {
 	*x = BaseRequest{}
-	mi := &file_protocol_proto_msgTypes[0]
+	mi := &file_protocol_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -152,7 +351,7 @@ func (x *BaseRequest) String() string {
 func (*BaseRequest) ProtoMessage() {}
 
 func (x *BaseRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_proto_msgTypes[0]
+	mi := &file_protocol_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -165,7 +364,7 @@ func (x *BaseRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BaseRequest.ProtoReflect.Descriptor instead.
 func (*BaseRequest) Descriptor() ([]byte, []int) {
-	return file_protocol_proto_rawDescGZIP(), []int{0}
+	return file_protocol_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *BaseRequest) GetType() ProtocolType {
@@ -182,22 +381,33 @@ func (x *BaseRequest) GetData() []byte {
 	return nil
 }
 
+func (x *BaseRequest) GetRequestId() uint64 {
+	if x != nil {
+		return x.RequestId
+	}
+	return 0
+}
+
 // 基础响应协议
 // 所有服务器响应的通用包装协议，包含结果状态和具体数据
 type BaseResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          ProtocolType           `protobuf:"varint,1,opt,name=type,proto3,enum=model.ProtocolType" json:"type,omitempty"`  // 协议类型，用于标识具体的响应类型
-	Result        RESP_CODE              `protobuf:"varint,2,opt,name=result,proto3,enum=model.RESP_CODE" json:"result,omitempty"` // 操作结果：SUCCESS(1)表示成功，ERROR(0)表示失败
-	Msg           string                 `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`                             // 结果消息，用于描述操作结果或错误信息
-	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`                           // 具体响应数据（序列化后的具体协议）
-	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                // 时间戳，记录响应生成的时间
+	Type          ProtocolType           `protobuf:"varint,1,opt,name=type,proto3,enum=model.ProtocolType" json:"type,omitempty"`                                                        // 协议类型，用于标识具体的响应类型
+	Result        RESP_CODE              `protobuf:"varint,2,opt,name=result,proto3,enum=model.RESP_CODE" json:"result,omitempty"`                                                       // 操作结果：SUCCESS(1)表示成功，ERROR(0)表示失败
+	Msg           string                 `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`                                                                                   // 结果消息，用于描述操作结果或错误信息
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`                                                                                 // 具体响应数据（序列化后的具体协议）
+	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                      // 时间戳，记录响应生成的时间
+	RequestId     uint64                 `protobuf:"varint,6,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`                                                     // 对应请求的请求ID，服务端推送或无法解析请求时为 0
+	Unsolicited   bool                   `protobuf:"varint,7,opt,name=unsolicited,proto3" json:"unsolicited,omitempty"`                                                                  // 是否为服务端主动推送，推送不对应任何请求
+	Code          ErrorCode              `protobuf:"varint,8,opt,name=code,proto3,enum=model.ErrorCode" json:"code,omitempty"`                                                           // 业务错误码，失败响应时有效
+	Details       map[string]string      `protobuf:"bytes,9,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 错误详情，例如出错的字段或分片续传的偏移
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BaseResponse) Reset() {
 	*x = BaseResponse{}
-	mi := &file_protocol_proto_msgTypes[1]
+	mi := &file_protocol_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -209,7 +419,7 @@ func (x *BaseResponse) String() string {
 func (*BaseResponse) ProtoMessage() {}
 
 func (x *BaseResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_proto_msgTypes[1]
+	mi := &file_protocol_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -222,7 +432,7 @@ func (x *BaseResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BaseResponse.ProtoReflect.Descriptor instead.
 func (*BaseResponse) Descriptor() ([]byte, []int) {
-	return file_protocol_proto_rawDescGZIP(), []int{1}
+	return file_protocol_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *BaseResponse) GetType() ProtocolType {
@@ -260,6 +470,34 @@ func (x *BaseResponse) GetTimestamp() int64 {
 	return 0
 }
 
+func (x *BaseResponse) GetRequestId() uint64 {
+	if x != nil {
+		return x.RequestId
+	}
+	return 0
+}
+
+func (x *BaseResponse) GetUnsolicited() bool {
+	if x != nil {
+		return x.Unsolicited
+	}
+	return false
+}
+
+func (x *BaseResponse) GetCode() ErrorCode {
+	if x != nil {
+		return x.Code
+	}
+	return ErrorCode_NO_ERROR
+}
+
+func (x *BaseResponse) GetDetails() map[string]string {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
 // 登录请求协议
 // 客户端发送的登录请求，包含微信小程序登录凭证
 type LoginRequest struct {
@@ -277,7 +515,7 @@ type LoginRequest struct {
 
 func (x *LoginRequest) Reset() {
 	*x = LoginRequest{}
-	mi := &file_protocol_proto_msgTypes[2]
+	mi := &file_protocol_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -289,7 +527,7 @@ func (x *LoginRequest) String() string {
 func (*LoginRequest) ProtoMessage() {}
 
 func (x *LoginRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_proto_msgTypes[2]
+	mi := &file_protocol_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -302,7 +540,7 @@ func (x *LoginRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LoginRequest.ProtoReflect.Descriptor instead.
 func (*LoginRequest) Descriptor() ([]byte, []int) {
-	return file_protocol_proto_rawDescGZIP(), []int{2}
+	return file_protocol_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *LoginRequest) GetBrand() string {
@@ -368,7 +606,7 @@ type LoginLabInfo struct {
 
 func (x *LoginLabInfo) Reset() {
 	*x = LoginLabInfo{}
-	mi := &file_protocol_proto_msgTypes[3]
+	mi := &file_protocol_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -380,7 +618,7 @@ func (x *LoginLabInfo) String() string {
 func (*LoginLabInfo) ProtoMessage() {}
 
 func (x *LoginLabInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_proto_msgTypes[3]
+	mi := &file_protocol_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -393,7 +631,7 @@ func (x *LoginLabInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LoginLabInfo.ProtoReflect.Descriptor instead.
 func (*LoginLabInfo) Descriptor() ([]byte, []int) {
-	return file_protocol_proto_rawDescGZIP(), []int{3}
+	return file_protocol_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *LoginLabInfo) GetLab() *Lab {
@@ -427,16 +665,19 @@ func (x *LoginLabInfo) GetUserRole() *Role {
 // 登录响应协议
 // 服务器返回的登录响应，包含用户信息和选中的实验室信息
 type LoginResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	User          *User                  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`       // 用户信息（引用user.proto中的User）
-	LabInfo       *LoginLabInfo          `protobuf:"bytes,2,opt,name=labInfo,proto3" json:"labInfo,omitempty"` // 用户当前选中的实验室信息（包含完整角色信息）
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	User            *User                  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`                                                 // 用户信息（引用user.proto中的User）
+	LabInfo         *LoginLabInfo          `protobuf:"bytes,2,opt,name=labInfo,proto3" json:"labInfo,omitempty"`                                           // 用户当前选中的实验室信息（包含完整角色信息）
+	SessionToken    string                 `protobuf:"bytes,3,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`             // 会话令牌，重连时通过 token 查询参数或 Authorization 头携带，免去重新登录
+	SessionExpireAt int64                  `protobuf:"varint,4,opt,name=session_expire_at,json=sessionExpireAt,proto3" json:"session_expire_at,omitempty"` // 会话令牌过期时间戳（Unix时间戳）
+	AppUpdate       *AppUpdateInfo         `protobuf:"bytes,5,opt,name=app_update,json=appUpdate,proto3" json:"app_update,omitempty"`                      // 建议升级时的升级信息，无需升级时为空
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *LoginResponse) Reset() {
 	*x = LoginResponse{}
-	mi := &file_protocol_proto_msgTypes[4]
+	mi := &file_protocol_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -448,7 +689,7 @@ func (x *LoginResponse) String() string {
 func (*LoginResponse) ProtoMessage() {}
 
 func (x *LoginResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_proto_msgTypes[4]
+	mi := &file_protocol_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -461,7 +702,7 @@ func (x *LoginResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LoginResponse.ProtoReflect.Descriptor instead.
 func (*LoginResponse) Descriptor() ([]byte, []int) {
-	return file_protocol_proto_rawDescGZIP(), []int{4}
+	return file_protocol_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *LoginResponse) GetUser() *User {
@@ -478,53 +719,963 @@ func (x *LoginResponse) GetLabInfo() *LoginLabInfo {
 	return nil
 }
 
-var File_protocol_proto protoreflect.FileDescriptor
+func (x *LoginResponse) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
 
-const file_protocol_proto_rawDesc = "" +
-	"\n" +
-	"\x0eprotocol.proto\x12\x05model\x1a\n" +
-	"user.proto\x1a\tlab.proto\x1a\n" +
-	"role.proto\"J\n" +
-	"\vBaseRequest\x12'\n" +
-	"\x04type\x18\x01 \x01(\x0e2\x13.model.ProtocolTypeR\x04type\x12\x12\n" +
-	"\x04data\x18\x02 \x01(\fR\x04data\"\xa5\x01\n" +
-	"\fBaseResponse\x12'\n" +
-	"\x04type\x18\x01 \x01(\x0e2\x13.model.ProtocolTypeR\x04type\x12(\n" +
-	"\x06result\x18\x02 \x01(\x0e2\x10.model.RESP_CODER\x06result\x12\x10\n" +
-	"\x03msg\x18\x03 \x01(\tR\x03msg\x12\x12\n" +
-	"\x04data\x18\x04 \x01(\fR\x04data\x12\x1c\n" +
-	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"\xd1\x01\n" +
-	"\fLoginRequest\x12\x14\n" +
-	"\x05brand\x18\x01 \x01(\tR\x05brand\x12\x14\n" +
-	"\x05model\x18\x02 \x01(\tR\x05model\x12%\n" +
-	"\x0ewechat_version\x18\x03 \x01(\tR\rwechatVersion\x12\x1a\n" +
-	"\bplatform\x18\x04 \x01(\tR\bplatform\x12\x1f\n" +
-	"\venv_version\x18\x05 \x01(\tR\n" +
-	"envVersion\x12\x18\n" +
-	"\aversion\x18\x06 \x01(\x05R\aversion\x12\x17\n" +
-	"\ajs_code\x18\a \x01(\tR\x06jsCode\"\x97\x01\n" +
-	"\fLoginLabInfo\x12\x1a\n" +
-	"\x03lab\x18\x01 \x01(\v2\b.lab.LabR\x03lab\x12 \n" +
-	"\x05roles\x18\x02 \x03(\v2\n" +
-	".role.RoleR\x05roles\x12 \n" +
-	"\fuser_role_id\x18\x03 \x01(\tR\n" +
-	"userRoleId\x12'\n" +
-	"\tuser_role\x18\x04 \x01(\v2\n" +
-	".role.RoleR\buserRole\"^\n" +
-	"\rLoginResponse\x12\x1e\n" +
-	"\x04user\x18\x01 \x01(\v2\n" +
-	".user.UserR\x04user\x12-\n" +
-	"\alabInfo\x18\x02 \x01(\v2\x13.model.LoginLabInfoR\alabInfo*:\n" +
-	"\fProtocolType\x12\v\n" +
-	"\aUNKNOWN\x10\x00\x12\r\n" +
-	"\tLOGIN_REQ\x10\x01\x12\x0e\n" +
+func (x *LoginResponse) GetSessionExpireAt() int64 {
+	if x != nil {
+		return x.SessionExpireAt
+	}
+	return 0
+}
+
+func (x *LoginResponse) GetAppUpdate() *AppUpdateInfo {
+	if x != nil {
+		return x.AppUpdate
+	}
+	return nil
+}
+
+// 开始分片传输请求
+// 大文件（图片、CSV 等）按分片上传，避免单条消息超过读取上限并长时间占用连接。
+// 续传时携带之前的 transfer_id，服务端返回已接收的字节数，客户端从该偏移继续发送
+type TransferBeginRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`    // 续传时传入之前的传输ID，新传输留空
+	FileName      string                 `protobuf:"bytes,2,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`          // 文件名
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // 文件类型，例如 image/png、text/csv
+	TotalSize     int64                  `protobuf:"varint,4,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`      // 文件总字节数
+	Sha256        string                 `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`                              // 整个文件的 SHA-256（十六进制小写），完成时校验
+	Purpose       string                 `protobuf:"bytes,6,opt,name=purpose,proto3" json:"purpose,omitempty"`                            // 用途，例如 order_attachment、bulk_import
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferBeginRequest) Reset() {
+	*x = TransferBeginRequest{}
+	mi := &file_protocol_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferBeginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferBeginRequest) ProtoMessage() {}
+
+func (x *TransferBeginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferBeginRequest.ProtoReflect.Descriptor instead.
+func (*TransferBeginRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TransferBeginRequest) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *TransferBeginRequest) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *TransferBeginRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *TransferBeginRequest) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+func (x *TransferBeginRequest) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *TransferBeginRequest) GetPurpose() string {
+	if x != nil {
+		return x.Purpose
+	}
+	return ""
+}
+
+// 开始分片传输响应
+type TransferBeginResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`        // 传输ID
+	ReceivedSize  int64                  `protobuf:"varint,2,opt,name=received_size,json=receivedSize,proto3" json:"received_size,omitempty"` // 服务端已接收的字节数，下一个分片的偏移
+	ChunkSize     int32                  `protobuf:"varint,3,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`          // 单个分片的最大字节数
+	ExpireAt      int64                  `protobuf:"varint,4,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`             // 未完成的传输过期时间戳（Unix时间戳）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferBeginResponse) Reset() {
+	*x = TransferBeginResponse{}
+	mi := &file_protocol_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferBeginResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferBeginResponse) ProtoMessage() {}
+
+func (x *TransferBeginResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferBeginResponse.ProtoReflect.Descriptor instead.
+func (*TransferBeginResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TransferBeginResponse) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *TransferBeginResponse) GetReceivedSize() int64 {
+	if x != nil {
+		return x.ReceivedSize
+	}
+	return 0
+}
+
+func (x *TransferBeginResponse) GetChunkSize() int32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *TransferBeginResponse) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
+// 上传分片请求
+// 分片必须按顺序发送，offset 等于服务端已接收的字节数
+type TransferChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"` // 传输ID
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`                          // 分片在文件中的偏移
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`                               // 分片数据
+	Crc32         uint32                 `protobuf:"varint,4,opt,name=crc32,proto3" json:"crc32,omitempty"`                            // 分片数据的 CRC32（IEEE）校验值
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferChunkRequest) Reset() {
+	*x = TransferChunkRequest{}
+	mi := &file_protocol_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferChunkRequest) ProtoMessage() {}
+
+func (x *TransferChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferChunkRequest.ProtoReflect.Descriptor instead.
+func (*TransferChunkRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TransferChunkRequest) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *TransferChunkRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *TransferChunkRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *TransferChunkRequest) GetCrc32() uint32 {
+	if x != nil {
+		return x.Crc32
+	}
+	return 0
+}
+
+// 上传分片响应
+type TransferChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`        // 传输ID
+	ReceivedSize  int64                  `protobuf:"varint,2,opt,name=received_size,json=receivedSize,proto3" json:"received_size,omitempty"` // 服务端已接收的字节数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferChunkResponse) Reset() {
+	*x = TransferChunkResponse{}
+	mi := &file_protocol_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferChunkResponse) ProtoMessage() {}
+
+func (x *TransferChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferChunkResponse.ProtoReflect.Descriptor instead.
+func (*TransferChunkResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TransferChunkResponse) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *TransferChunkResponse) GetReceivedSize() int64 {
+	if x != nil {
+		return x.ReceivedSize
+	}
+	return 0
+}
+
+// 完成分片传输请求
+type TransferFinishRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"` // 传输ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferFinishRequest) Reset() {
+	*x = TransferFinishRequest{}
+	mi := &file_protocol_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferFinishRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferFinishRequest) ProtoMessage() {}
+
+func (x *TransferFinishRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferFinishRequest.ProtoReflect.Descriptor instead.
+func (*TransferFinishRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TransferFinishRequest) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+// 完成分片传输响应
+type TransferFinishResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"` // 传输ID，业务请求（如订单附件、批量导入）通过该ID引用已上传的文件
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`                              // 文件总字节数
+	Sha256        string                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`                           // 文件的 SHA-256
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferFinishResponse) Reset() {
+	*x = TransferFinishResponse{}
+	mi := &file_protocol_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferFinishResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferFinishResponse) ProtoMessage() {}
+
+func (x *TransferFinishResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferFinishResponse.ProtoReflect.Descriptor instead.
+func (*TransferFinishResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TransferFinishResponse) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *TransferFinishResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *TransferFinishResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+// 服务端推送消息
+// 服务端主动发送的通知，客户端收到后需要发送 PUSH_ACK_REQ 确认；
+// 未确认的推送会重试投递直到过期，用户离线时保存，下次登录后投递。
+// 同一推送可能被投递多次，客户端应按 id 去重
+type PushMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// @gotags: json:"id,omitempty" bson:"_id"
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty" bson:"_id"` // 推送消息ID，确认时原样返回
+	// @gotags: json:"user_id,omitempty" bson:"user_id"
+	UserId    string `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty" bson:"user_id"`           // 接收用户ID
+	Kind      string `protobuf:"bytes,3,opt,name=kind,proto3" json:"kind,omitempty"`                             // 推送类型，例如 order.created，客户端据此解析 data
+	Data      []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`                             // 推送数据（序列化后的具体协议）
+	CreatedAt int64  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // 创建时间戳（Unix时间戳）
+	// @gotags: json:"expire_at,omitempty" bson:"expire_at"
+	ExpireAt      int64 `protobuf:"varint,6,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty" bson:"expire_at"` // 过期时间戳（Unix时间戳），过期后不再投递
+	Attempts      int32 `protobuf:"varint,7,opt,name=attempts,proto3" json:"attempts,omitempty"`                 // 已投递次数，从 1 开始
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushMessage) Reset() {
+	*x = PushMessage{}
+	mi := &file_protocol_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushMessage) ProtoMessage() {}
+
+func (x *PushMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushMessage.ProtoReflect.Descriptor instead.
+func (*PushMessage) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PushMessage) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PushMessage) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *PushMessage) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *PushMessage) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *PushMessage) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *PushMessage) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
+func (x *PushMessage) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+// 推送确认请求
+// 客户端确认已收到的推送，可以批量确认
+type PushAckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"` // 已收到的推送消息ID列表
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushAckRequest) Reset() {
+	*x = PushAckRequest{}
+	mi := &file_protocol_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushAckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushAckRequest) ProtoMessage() {}
+
+func (x *PushAckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushAckRequest.ProtoReflect.Descriptor instead.
+func (*PushAckRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PushAckRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// 推送确认响应
+type PushAckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Acked         int32                  `protobuf:"varint,1,opt,name=acked,proto3" json:"acked,omitempty"` // 本次确认的推送数量，重复确认或已过期的推送不计入
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushAckResponse) Reset() {
+	*x = PushAckResponse{}
+	mi := &file_protocol_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushAckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushAckResponse) ProtoMessage() {}
+
+func (x *PushAckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushAckResponse.ProtoReflect.Descriptor instead.
+func (*PushAckResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PushAckResponse) GetAcked() int32 {
+	if x != nil {
+		return x.Acked
+	}
+	return 0
+}
+
+// 心跳请求
+// 小程序无法可靠地响应 WebSocket Ping 帧，需定期发送应用层心跳保持连接，间隔应小于服务端读超时
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientTime    int64                  `protobuf:"varint,1,opt,name=client_time,json=clientTime,proto3" json:"client_time,omitempty"` // 客户端发送时间（毫秒时间戳），在响应中原样返回，用于计算往返时延
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_protocol_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *HeartbeatRequest) GetClientTime() int64 {
+	if x != nil {
+		return x.ClientTime
+	}
+	return 0
+}
+
+// 心跳响应
+// 客户端可根据 server_time 和往返时延校正本地时钟偏差
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerTime    int64                  `protobuf:"varint,1,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`                                                     // 服务端时间（毫秒时间戳）
+	ClientTime    int64                  `protobuf:"varint,2,opt,name=client_time,json=clientTime,proto3" json:"client_time,omitempty"`                                                     // 请求中的客户端发送时间
+	Counters      map[string]int32       `protobuf:"bytes,3,rep,name=counters,proto3" json:"counters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // 轻量状态计数，例如未读消息数，计数为 0 时不返回
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_protocol_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *HeartbeatResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+func (x *HeartbeatResponse) GetClientTime() int64 {
+	if x != nil {
+		return x.ClientTime
+	}
+	return 0
+}
+
+func (x *HeartbeatResponse) GetCounters() map[string]int32 {
+	if x != nil {
+		return x.Counters
+	}
+	return nil
+}
+
+// 取消请求
+// 取消同一连接上仍在处理中的请求，被取消的请求仍会收到响应：处理已完成时为原结果，否则为 CANCELED 错误
+type CancelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     uint64                 `protobuf:"varint,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // 要取消的请求的请求ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_protocol_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CancelRequest) GetRequestId() uint64 {
+	if x != nil {
+		return x.RequestId
+	}
+	return 0
+}
+
+// 取消请求响应
+type CancelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Canceled      bool                   `protobuf:"varint,1,opt,name=canceled,proto3" json:"canceled,omitempty"` // 请求是否仍在处理中并已取消，请求已完成或不存在时为 false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelResponse) Reset() {
+	*x = CancelResponse{}
+	mi := &file_protocol_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelResponse) ProtoMessage() {}
+
+func (x *CancelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelResponse.ProtoReflect.Descriptor instead.
+func (*CancelResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CancelResponse) GetCanceled() bool {
+	if x != nil {
+		return x.Canceled
+	}
+	return false
+}
+
+var File_protocol_proto protoreflect.FileDescriptor
+
+const file_protocol_proto_rawDesc = "" +
+	"\n" +
+	"\x0eprotocol.proto\x12\x05model\x1a\n" +
+	"user.proto\x1a\tlab.proto\x1a\n" +
+	"role.proto\"\x87\x01\n" +
+	"\rAppUpdateInfo\x12\x14\n" +
+	"\x05force\x18\x01 \x01(\bR\x05force\x12\x1f\n" +
+	"\vmin_version\x18\x02 \x01(\x05R\n" +
+	"minVersion\x12%\n" +
+	"\x0elatest_version\x18\x03 \x01(\x05R\rlatestVersion\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"i\n" +
+	"\vBaseRequest\x12'\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x13.model.ProtocolTypeR\x04type\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\x04R\trequestId\"\x84\x03\n" +
+	"\fBaseResponse\x12'\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x13.model.ProtocolTypeR\x04type\x12(\n" +
+	"\x06result\x18\x02 \x01(\x0e2\x10.model.RESP_CODER\x06result\x12\x10\n" +
+	"\x03msg\x18\x03 \x01(\tR\x03msg\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x06 \x01(\x04R\trequestId\x12 \n" +
+	"\vunsolicited\x18\a \x01(\bR\vunsolicited\x12$\n" +
+	"\x04code\x18\b \x01(\x0e2\x10.model.ErrorCodeR\x04code\x12:\n" +
+	"\adetails\x18\t \x03(\v2 .model.BaseResponse.DetailsEntryR\adetails\x1a:\n" +
+	"\fDetailsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd1\x01\n" +
+	"\fLoginRequest\x12\x14\n" +
+	"\x05brand\x18\x01 \x01(\tR\x05brand\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12%\n" +
+	"\x0ewechat_version\x18\x03 \x01(\tR\rwechatVersion\x12\x1a\n" +
+	"\bplatform\x18\x04 \x01(\tR\bplatform\x12\x1f\n" +
+	"\venv_version\x18\x05 \x01(\tR\n" +
+	"envVersion\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x05R\aversion\x12\x17\n" +
+	"\ajs_code\x18\a \x01(\tR\x06jsCode\"\x97\x01\n" +
+	"\fLoginLabInfo\x12\x1a\n" +
+	"\x03lab\x18\x01 \x01(\v2\b.lab.LabR\x03lab\x12 \n" +
+	"\x05roles\x18\x02 \x03(\v2\n" +
+	".role.RoleR\x05roles\x12 \n" +
+	"\fuser_role_id\x18\x03 \x01(\tR\n" +
+	"userRoleId\x12'\n" +
+	"\tuser_role\x18\x04 \x01(\v2\n" +
+	".role.RoleR\buserRole\"\xe4\x01\n" +
+	"\rLoginResponse\x12\x1e\n" +
+	"\x04user\x18\x01 \x01(\v2\n" +
+	".user.UserR\x04user\x12-\n" +
+	"\alabInfo\x18\x02 \x01(\v2\x13.model.LoginLabInfoR\alabInfo\x12#\n" +
+	"\rsession_token\x18\x03 \x01(\tR\fsessionToken\x12*\n" +
+	"\x11session_expire_at\x18\x04 \x01(\x03R\x0fsessionExpireAt\x123\n" +
+	"\n" +
+	"app_update\x18\x05 \x01(\v2\x14.model.AppUpdateInfoR\tappUpdate\"\xc8\x01\n" +
+	"\x14TransferBeginRequest\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12\x1b\n" +
+	"\tfile_name\x18\x02 \x01(\tR\bfileName\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x04 \x01(\x03R\ttotalSize\x12\x16\n" +
+	"\x06sha256\x18\x05 \x01(\tR\x06sha256\x12\x18\n" +
+	"\apurpose\x18\x06 \x01(\tR\apurpose\"\x99\x01\n" +
+	"\x15TransferBeginResponse\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12#\n" +
+	"\rreceived_size\x18\x02 \x01(\x03R\freceivedSize\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x03 \x01(\x05R\tchunkSize\x12\x1b\n" +
+	"\texpire_at\x18\x04 \x01(\x03R\bexpireAt\"y\n" +
+	"\x14TransferChunkRequest\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x14\n" +
+	"\x05crc32\x18\x04 \x01(\rR\x05crc32\"]\n" +
+	"\x15TransferChunkResponse\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12#\n" +
+	"\rreceived_size\x18\x02 \x01(\x03R\freceivedSize\"8\n" +
+	"\x15TransferFinishRequest\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\"e\n" +
+	"\x16TransferFinishResponse\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\"\xb6\x01\n" +
+	"\vPushMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04kind\x18\x03 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\x03R\tcreatedAt\x12\x1b\n" +
+	"\texpire_at\x18\x06 \x01(\x03R\bexpireAt\x12\x1a\n" +
+	"\battempts\x18\a \x01(\x05R\battempts\"\"\n" +
+	"\x0ePushAckRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"'\n" +
+	"\x0fPushAckResponse\x12\x14\n" +
+	"\x05acked\x18\x01 \x01(\x05R\x05acked\"3\n" +
+	"\x10HeartbeatRequest\x12\x1f\n" +
+	"\vclient_time\x18\x01 \x01(\x03R\n" +
+	"clientTime\"\xd6\x01\n" +
+	"\x11HeartbeatResponse\x12\x1f\n" +
+	"\vserver_time\x18\x01 \x01(\x03R\n" +
+	"serverTime\x12\x1f\n" +
+	"\vclient_time\x18\x02 \x01(\x03R\n" +
+	"clientTime\x12B\n" +
+	"\bcounters\x18\x03 \x03(\v2&.model.HeartbeatResponse.CountersEntryR\bcounters\x1a;\n" +
+	"\rCountersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\".\n" +
+	"\rCancelRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\x04R\trequestId\",\n" +
+	"\x0eCancelResponse\x12\x1a\n" +
+	"\bcanceled\x18\x01 \x01(\bR\bcanceled*\xc6\x02\n" +
+	"\fProtocolType\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\r\n" +
+	"\tLOGIN_REQ\x10\x01\x12\x0e\n" +
+	"\n" +
+	"LOGIN_RESP\x10\x02\x12\x16\n" +
+	"\x12TRANSFER_BEGIN_REQ\x10\n" +
+	"\x12\x17\n" +
+	"\x13TRANSFER_BEGIN_RESP\x10\v\x12\x16\n" +
+	"\x12TRANSFER_CHUNK_REQ\x10\f\x12\x17\n" +
+	"\x13TRANSFER_CHUNK_RESP\x10\r\x12\x17\n" +
+	"\x13TRANSFER_FINISH_REQ\x10\x0e\x12\x18\n" +
+	"\x14TRANSFER_FINISH_RESP\x10\x0f\x12\b\n" +
+	"\x04PUSH\x10\x14\x12\x10\n" +
+	"\fPUSH_ACK_REQ\x10\x15\x12\x11\n" +
+	"\rPUSH_ACK_RESP\x10\x16\x12\x11\n" +
+	"\rHEARTBEAT_REQ\x10\x1e\x12\x12\n" +
+	"\x0eHEARTBEAT_RESP\x10\x1f\x12\x0e\n" +
 	"\n" +
-	"LOGIN_RESP\x10\x02*3\n" +
+	"CANCEL_REQ\x10(\x12\x0f\n" +
+	"\vCANCEL_RESP\x10)*3\n" +
 	"\tRESP_CODE\x12\t\n" +
 	"\x05ERROR\x10\x00\x12\v\n" +
 	"\aSUCCESS\x10\x01\x12\x0e\n" +
 	"\n" +
-	"APP_UPDATE\x10\x03B\x1fZ\x1dhappyAssistant/internal/modelb\x06proto3"
+	"APP_UPDATE\x10\x03*\x9a\x02\n" +
+	"\tErrorCode\x12\f\n" +
+	"\bNO_ERROR\x10\x00\x12\f\n" +
+	"\bINTERNAL\x10\x01\x12\x14\n" +
+	"\x10INVALID_ARGUMENT\x10\x02\x12\r\n" +
+	"\tNOT_FOUND\x10\x03\x12\x12\n" +
+	"\x0eALREADY_EXISTS\x10\x04\x12\x11\n" +
+	"\rNOT_LOGGED_IN\x10\x05\x12\x15\n" +
+	"\x11PERMISSION_DENIED\x10\x06\x12\x10\n" +
+	"\fRATE_LIMITED\x10\a\x12\x17\n" +
+	"\x13FAILED_PRECONDITION\x10\b\x12\x15\n" +
+	"\x11PAYLOAD_TOO_LARGE\x10\t\x12\x14\n" +
+	"\x10UNKNOWN_PROTOCOL\x10\n" +
+	"\x12\x0f\n" +
+	"\vUNAVAILABLE\x10\v\x12\x17\n" +
+	"\x13APP_UPDATE_REQUIRED\x10\f\x12\f\n" +
+	"\bCANCELED\x10\rB\x1fZ\x1dhappyAssistant/internal/modelb\x06proto3"
 
 var (
 	file_protocol_proto_rawDescOnce sync.Once
@@ -538,34 +1689,55 @@ func file_protocol_proto_rawDescGZIP() []byte {
 	return file_protocol_proto_rawDescData
 }
 
-var file_protocol_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_protocol_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_protocol_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_protocol_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file_protocol_proto_goTypes = []any{
-	(ProtocolType)(0),     // 0: model.ProtocolType
-	(RESP_CODE)(0),        // 1: model.RESP_CODE
-	(*BaseRequest)(nil),   // 2: model.BaseRequest
-	(*BaseResponse)(nil),  // 3: model.BaseResponse
-	(*LoginRequest)(nil),  // 4: model.LoginRequest
-	(*LoginLabInfo)(nil),  // 5: model.LoginLabInfo
-	(*LoginResponse)(nil), // 6: model.LoginResponse
-	(*Lab)(nil),           // 7: lab.Lab
-	(*Role)(nil),          // 8: role.Role
-	(*User)(nil),          // 9: user.User
+	(ProtocolType)(0),              // 0: model.ProtocolType
+	(RESP_CODE)(0),                 // 1: model.RESP_CODE
+	(ErrorCode)(0),                 // 2: model.ErrorCode
+	(*AppUpdateInfo)(nil),          // 3: model.AppUpdateInfo
+	(*BaseRequest)(nil),            // 4: model.BaseRequest
+	(*BaseResponse)(nil),           // 5: model.BaseResponse
+	(*LoginRequest)(nil),           // 6: model.LoginRequest
+	(*LoginLabInfo)(nil),           // 7: model.LoginLabInfo
+	(*LoginResponse)(nil),          // 8: model.LoginResponse
+	(*TransferBeginRequest)(nil),   // 9: model.TransferBeginRequest
+	(*TransferBeginResponse)(nil),  // 10: model.TransferBeginResponse
+	(*TransferChunkRequest)(nil),   // 11: model.TransferChunkRequest
+	(*TransferChunkResponse)(nil),  // 12: model.TransferChunkResponse
+	(*TransferFinishRequest)(nil),  // 13: model.TransferFinishRequest
+	(*TransferFinishResponse)(nil), // 14: model.TransferFinishResponse
+	(*PushMessage)(nil),            // 15: model.PushMessage
+	(*PushAckRequest)(nil),         // 16: model.PushAckRequest
+	(*PushAckResponse)(nil),        // 17: model.PushAckResponse
+	(*HeartbeatRequest)(nil),       // 18: model.HeartbeatRequest
+	(*HeartbeatResponse)(nil),      // 19: model.HeartbeatResponse
+	(*CancelRequest)(nil),          // 20: model.CancelRequest
+	(*CancelResponse)(nil),         // 21: model.CancelResponse
+	nil,                            // 22: model.BaseResponse.DetailsEntry
+	nil,                            // 23: model.HeartbeatResponse.CountersEntry
+	(*Lab)(nil),                    // 24: lab.Lab
+	(*Role)(nil),                   // 25: role.Role
+	(*User)(nil),                   // 26: user.User
 }
 var file_protocol_proto_depIdxs = []int32{
-	0, // 0: model.BaseRequest.type:type_name -> model.ProtocolType
-	0, // 1: model.BaseResponse.type:type_name -> model.ProtocolType
-	1, // 2: model.BaseResponse.result:type_name -> model.RESP_CODE
-	7, // 3: model.LoginLabInfo.lab:type_name -> lab.Lab
-	8, // 4: model.LoginLabInfo.roles:type_name -> role.Role
-	8, // 5: model.LoginLabInfo.user_role:type_name -> role.Role
-	9, // 6: model.LoginResponse.user:type_name -> user.User
-	5, // 7: model.LoginResponse.labInfo:type_name -> model.LoginLabInfo
-	8, // [8:8] is the sub-list for method output_type
-	8, // [8:8] is the sub-list for method input_type
-	8, // [8:8] is the sub-list for extension type_name
-	8, // [8:8] is the sub-list for extension extendee
-	0, // [0:8] is the sub-list for field type_name
+	0,  // 0: model.BaseRequest.type:type_name -> model.ProtocolType
+	0,  // 1: model.BaseResponse.type:type_name -> model.ProtocolType
+	1,  // 2: model.BaseResponse.result:type_name -> model.RESP_CODE
+	2,  // 3: model.BaseResponse.code:type_name -> model.ErrorCode
+	22, // 4: model.BaseResponse.details:type_name -> model.BaseResponse.DetailsEntry
+	24, // 5: model.LoginLabInfo.lab:type_name -> lab.Lab
+	25, // 6: model.LoginLabInfo.roles:type_name -> role.Role
+	25, // 7: model.LoginLabInfo.user_role:type_name -> role.Role
+	26, // 8: model.LoginResponse.user:type_name -> user.User
+	7,  // 9: model.LoginResponse.labInfo:type_name -> model.LoginLabInfo
+	3,  // 10: model.LoginResponse.app_update:type_name -> model.AppUpdateInfo
+	23, // 11: model.HeartbeatResponse.counters:type_name -> model.HeartbeatResponse.CountersEntry
+	12, // [12:12] is the sub-list for method output_type
+	12, // [12:12] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_protocol_proto_init() }
@@ -581,8 +1753,8 @@ func file_protocol_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protocol_proto_rawDesc), len(file_protocol_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   5,
+			NumEnums:      3,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
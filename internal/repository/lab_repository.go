@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"happyAssistant/internal/initialize"
 	"happyAssistant/internal/model"
 
@@ -24,14 +25,14 @@ func NewLabRepository() *LabRepository {
 }
 
 // GetDefaultLab 获取默认实验室
-func (lr *LabRepository) GetDefaultLab() (*model.Lab, error) {
+func (lr *LabRepository) GetDefaultLab(ctx context.Context) (*model.Lab, error) {
 	log.Info("Getting default lab")
 	filter := bson.M{"is_default": true}
-	result, err := FindOne[*model.Lab](lr.collection, filter)
+	result, err := FindOne[*model.Lab](ctx, lr.collection, filter)
 	if err != nil {
 		// 如果没有默认实验室，返回第一个实验室
 		filter = bson.M{}
-		result, err = FindOne[*model.Lab](lr.collection, filter)
+		result, err = FindOne[*model.Lab](ctx, lr.collection, filter)
 		if err != nil {
 			return nil, err
 		}
@@ -40,10 +41,10 @@ func (lr *LabRepository) GetDefaultLab() (*model.Lab, error) {
 }
 
 // FindByID 根据ID查找实验室
-func (lr *LabRepository) FindByID(labID string) (*model.Lab, error) {
+func (lr *LabRepository) FindByID(ctx context.Context, labID string) (*model.Lab, error) {
 	log.Infof("Finding lab by ID: %s", labID)
 	filter := bson.M{"_id": labID}
-	result, err := FindOne[*model.Lab](lr.collection, filter)
+	result, err := FindOne[*model.Lab](ctx, lr.collection, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -51,15 +52,15 @@ func (lr *LabRepository) FindByID(labID string) (*model.Lab, error) {
 }
 
 // Create 创建实验室
-func (lr *LabRepository) Create(lab *model.Lab) error {
+func (lr *LabRepository) Create(ctx context.Context, lab *model.Lab) error {
 	log.Infof("Creating lab: %s", lab.Id)
-	return InsertOne(lr.collection, lab)
+	return InsertOne(ctx, lr.collection, lab)
 }
 
 // FindAll 查找所有实验室
-func (lr *LabRepository) FindAll() ([]*model.Lab, error) {
+func (lr *LabRepository) FindAll(ctx context.Context) ([]*model.Lab, error) {
 	log.Info("Finding all labs")
-	results, err := FindMany[*model.Lab](lr.collection, bson.M{})
+	results, err := FindMany[*model.Lab](ctx, lr.collection, bson.M{})
 	if err != nil {
 		return nil, err
 	}
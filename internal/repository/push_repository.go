@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"happyAssistant/internal/initialize"
+	"happyAssistant/internal/model"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// PushRepository 推送消息数据访问层
+// 保存尚未被客户端确认的推送，确认后删除
+type PushRepository struct {
+	collection *mongo.Collection
+}
+
+// NewPushRepository 创建推送消息仓库实例
+func NewPushRepository() *PushRepository {
+	client := initialize.GetMongoClient()
+	collection := client.Collection("pushes")
+	return &PushRepository{
+		collection: collection,
+	}
+}
+
+// Create 保存推送消息
+func (pr *PushRepository) Create(ctx context.Context, msg *model.PushMessage) error {
+	return InsertOne(ctx, pr.collection, msg)
+}
+
+// FindPending 获取用户未过期的推送消息，按创建时间排序
+func (pr *PushRepository) FindPending(ctx context.Context, userID string, now int64) ([]*model.PushMessage, error) {
+	filter := bson.M{"user_id": userID, "expire_at": bson.M{"$gt": now}}
+	results, err := FindMany[*model.PushMessage](ctx, pr.collection, filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].CreatedAt < results[j].CreatedAt
+	})
+	return results, nil
+}
+
+// DeleteAcked 删除用户已确认的推送消息
+func (pr *PushRepository) DeleteAcked(ctx context.Context, userID string, ids []string) error {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "user_id": userID}
+	return DeleteMany(ctx, pr.collection, filter)
+}
+
+// DeleteExpired 删除已过期的推送消息
+func (pr *PushRepository) DeleteExpired(ctx context.Context, now int64) error {
+	log.Debugf("Deleting expired pushes before %d", now)
+	filter := bson.M{"expire_at": bson.M{"$lte": now}}
+	return DeleteMany(ctx, pr.collection, filter)
+}
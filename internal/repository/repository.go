@@ -13,15 +13,18 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+// 以下泛型操作都以调用方传入的 ctx 为父上下文，并叠加单次操作超时（mongodb.opTimeout），
+// 请求被取消或超时后数据库操作随之结束
+
 // InsertOne 插入单个文档
 // T 必须是 proto.Message 的指针类型
-func InsertOne[T proto.Message](collection *mongo.Collection, data T) error {
+func InsertOne[T proto.Message](ctx context.Context, collection *mongo.Collection, data T) error {
 	// 检查data是否为nil - 使用反射检查，因为泛型类型不能直接与nil比较
 	if reflect.ValueOf(data).IsNil() {
 		return fmt.Errorf("cannot insert nil document")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 
 	// 将泛型类型转换为interface{}，MongoDB驱动会处理序列化
@@ -35,8 +38,8 @@ func InsertOne[T proto.Message](collection *mongo.Collection, data T) error {
 
 // InsertMany 插入多个文档
 // T 必须是 proto.Message 的指针类型
-func InsertMany[T proto.Message](collection *mongo.Collection, data []T) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func InsertMany[T proto.Message](ctx context.Context, collection *mongo.Collection, data []T) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 
 	// 检查data是否为空或包含nil元素
@@ -61,16 +64,16 @@ func InsertMany[T proto.Message](collection *mongo.Collection, data []T) error {
 }
 
 // DeleteOne 删除单个文档
-func DeleteOne(collection *mongo.Collection, filter interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func DeleteOne(ctx context.Context, collection *mongo.Collection, filter interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 	_, err := collection.DeleteOne(ctx, filter)
 	return err
 }
 
 // DeleteMany 删除多个文档
-func DeleteMany(collection *mongo.Collection, filter interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func DeleteMany(ctx context.Context, collection *mongo.Collection, filter interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 	_, err := collection.DeleteMany(ctx, filter)
 	return err
@@ -78,8 +81,8 @@ func DeleteMany(collection *mongo.Collection, filter interface{}) error {
 
 // FindOne 查找单个文档
 // T 必须是 proto.Message 的指针类型
-func FindOne[T proto.Message](collection *mongo.Collection, filter interface{}) (T, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func FindOne[T proto.Message](ctx context.Context, collection *mongo.Collection, filter interface{}) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 
 	// 创建T类型的新实例
@@ -104,8 +107,8 @@ func FindOne[T proto.Message](collection *mongo.Collection, filter interface{})
 
 // FindMany 查找多个文档
 // T 必须是 proto.Message 的指针类型
-func FindMany[T proto.Message](collection *mongo.Collection, filter interface{}) ([]T, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func FindMany[T proto.Message](ctx context.Context, collection *mongo.Collection, filter interface{}) ([]T, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 
 	cur, err := collection.Find(ctx, filter)
@@ -121,9 +124,9 @@ func FindMany[T proto.Message](collection *mongo.Collection, filter interface{})
 
 	var results []T
 	for cur.Next(ctx) {
-		// 创建T类型的新实例
-		var elem T
-		elem = proto.Clone(elem).(T)
+		// 创建T类型的新实例，T 的零值为 nil 指针，需要通过反射类型创建
+		var zero T
+		elem := zero.ProtoReflect().Type().New().Interface().(T)
 
 		if err := cur.Decode(elem); err != nil {
 			return nil, err
@@ -137,16 +140,16 @@ func FindMany[T proto.Message](collection *mongo.Collection, filter interface{})
 }
 
 // UpdateOne 更新单个文档
-func UpdateOne(collection *mongo.Collection, filter interface{}, update interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func UpdateOne(ctx context.Context, collection *mongo.Collection, filter interface{}, update interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 	_, err := collection.UpdateOne(ctx, filter, update)
 	return err
 }
 
 // UpdateMany 更新多个文档
-func UpdateMany(collection *mongo.Collection, filter interface{}, update interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func UpdateMany(ctx context.Context, collection *mongo.Collection, filter interface{}, update interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 	_, err := collection.UpdateMany(ctx, filter, update)
 	return err
@@ -154,21 +157,21 @@ func UpdateMany(collection *mongo.Collection, filter interface{}, update interfa
 
 // ReplaceOne 替换单个文档
 // T 必须是 proto.Message 的指针类型
-func ReplaceOne[T proto.Message](collection *mongo.Collection, filter interface{}, replacement T) error {
+func ReplaceOne[T proto.Message](ctx context.Context, collection *mongo.Collection, filter interface{}, replacement T) error {
 	// 检查replacement是否为nil
 	if reflect.ValueOf(replacement).IsNil() {
 		return fmt.Errorf("cannot replace with nil document")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 	_, err := collection.ReplaceOne(ctx, filter, replacement)
 	return err
 }
 
 // Count 统计文档数量
-func Count(collection *mongo.Collection, filter interface{}) (int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.MongoDB.OpTimeout)
+func Count(ctx context.Context, collection *mongo.Collection, filter interface{}) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.MongoDB.OpTimeout)
 	defer cancel()
 	return collection.CountDocuments(ctx, filter)
 }
@@ -9,6 +9,7 @@ type RepositoryManager struct {
 	userRepo *UserRepository
 	labRepo  *LabRepository
 	roleRepo *RoleRepository
+	pushRepo *PushRepository
 }
 
 var (
@@ -23,6 +24,7 @@ func GetRepositoryManager() *RepositoryManager {
 			userRepo: NewUserRepository(),
 			labRepo:  NewLabRepository(),
 			roleRepo: NewRoleRepository(),
+			pushRepo: NewPushRepository(),
 		}
 	})
 	return repositoryManager
@@ -42,3 +44,8 @@ func (rm *RepositoryManager) GetLabRepository() *LabRepository {
 func (rm *RepositoryManager) GetRoleRepository() *RoleRepository {
 	return rm.roleRepo
 }
+
+// GetPushRepository 获取推送消息仓库
+func (rm *RepositoryManager) GetPushRepository() *PushRepository {
+	return rm.pushRepo
+}
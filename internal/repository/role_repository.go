@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"happyAssistant/internal/initialize"
 	"happyAssistant/internal/model"
 
@@ -24,10 +25,10 @@ func NewRoleRepository() *RoleRepository {
 }
 
 // GetRolesByLabID 根据实验室ID获取角色列表
-func (rr *RoleRepository) GetRolesByLabID(labID string) ([]*model.Role, error) {
+func (rr *RoleRepository) GetRolesByLabID(ctx context.Context, labID string) ([]*model.Role, error) {
 	log.Infof("Getting roles by lab ID: %s", labID)
 	filter := bson.M{"lab_id": labID}
-	results, err := FindMany[*model.Role](rr.collection, filter)
+	results, err := FindMany[*model.Role](ctx, rr.collection, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -35,10 +36,10 @@ func (rr *RoleRepository) GetRolesByLabID(labID string) ([]*model.Role, error) {
 }
 
 // FindByID 根据ID查找角色
-func (rr *RoleRepository) FindByID(roleID string) (*model.Role, error) {
+func (rr *RoleRepository) FindByID(ctx context.Context, roleID string) (*model.Role, error) {
 	log.Infof("Finding role by ID: %s", roleID)
 	filter := bson.M{"_id": roleID}
-	result, err := FindOne[*model.Role](rr.collection, filter)
+	result, err := FindOne[*model.Role](ctx, rr.collection, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -46,15 +47,15 @@ func (rr *RoleRepository) FindByID(roleID string) (*model.Role, error) {
 }
 
 // Create 创建角色
-func (rr *RoleRepository) Create(role *model.Role) error {
+func (rr *RoleRepository) Create(ctx context.Context, role *model.Role) error {
 	log.Infof("Creating role: %s", role.Id)
-	return InsertOne(rr.collection, role)
+	return InsertOne(ctx, rr.collection, role)
 }
 
 // FindAll 查找所有角色
-func (rr *RoleRepository) FindAll() ([]*model.Role, error) {
+func (rr *RoleRepository) FindAll(ctx context.Context) ([]*model.Role, error) {
 	log.Info("Finding all roles")
-	results, err := FindMany[*model.Role](rr.collection, bson.M{})
+	results, err := FindMany[*model.Role](ctx, rr.collection, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"happyAssistant/internal/initialize"
 	"happyAssistant/internal/model"
 
@@ -25,16 +26,16 @@ func NewUserRepository() *UserRepository {
 }
 
 // Create 创建新用户
-func (ur *UserRepository) Create(user *model.User) error {
+func (ur *UserRepository) Create(ctx context.Context, user *model.User) error {
 	log.Infof("Creating user: %s", user.Id)
-	return InsertOne(ur.collection, user)
+	return InsertOne(ctx, ur.collection, user)
 }
 
 // FindByID 根据用户ID查找用户
-func (ur *UserRepository) FindByID(userID string) (*model.User, error) {
+func (ur *UserRepository) FindByID(ctx context.Context, userID string) (*model.User, error) {
 	log.Infof("Finding user by ID: %s", userID)
 	filter := bson.M{"_id": userID}
-	result, err := FindOne[*model.User](ur.collection, filter)
+	result, err := FindOne[*model.User](ctx, ur.collection, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -42,10 +43,10 @@ func (ur *UserRepository) FindByID(userID string) (*model.User, error) {
 }
 
 // FindByOpenID 根据微信OpenID查找用户
-func (ur *UserRepository) FindByOpenID(openID string) (*model.User, error) {
+func (ur *UserRepository) FindByOpenID(ctx context.Context, openID string) (*model.User, error) {
 	log.Infof("Finding user by OpenID: %s", openID)
 	filter := bson.M{"open_id": openID}
-	result, err := FindOne[*model.User](ur.collection, filter)
+	result, err := FindOne[*model.User](ctx, ur.collection, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -53,23 +54,23 @@ func (ur *UserRepository) FindByOpenID(openID string) (*model.User, error) {
 }
 
 // Update 更新用户信息
-func (ur *UserRepository) Update(user *model.User) error {
+func (ur *UserRepository) Update(ctx context.Context, user *model.User) error {
 	log.Infof("Updating user: %s", user.Id)
 	filter := bson.M{"_id": user.Id}
-	return ReplaceOne(ur.collection, filter, user)
+	return ReplaceOne(ctx, ur.collection, filter, user)
 }
 
 // Delete 删除用户
-func (ur *UserRepository) Delete(userID string) error {
+func (ur *UserRepository) Delete(ctx context.Context, userID string) error {
 	log.Infof("Deleting user: %s", userID)
 	filter := bson.M{"_id": userID}
-	return DeleteOne(ur.collection, filter)
+	return DeleteOne(ctx, ur.collection, filter)
 }
 
 // FindAll 查找所有用户
-func (ur *UserRepository) FindAll() ([]*model.User, error) {
+func (ur *UserRepository) FindAll(ctx context.Context) ([]*model.User, error) {
 	log.Info("Finding all users")
-	results, err := FindMany[*model.User](ur.collection, bson.M{})
+	results, err := FindMany[*model.User](ctx, ur.collection, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -77,10 +78,10 @@ func (ur *UserRepository) FindAll() ([]*model.User, error) {
 }
 
 // FindByLabID 根据实验室ID查找用户
-func (ur *UserRepository) FindByLabID(labID string) ([]*model.User, error) {
+func (ur *UserRepository) FindByLabID(ctx context.Context, labID string) ([]*model.User, error) {
 	log.Infof("Finding users by lab ID: %s", labID)
 	filter := bson.M{"lib_ids": labID}
-	results, err := FindMany[*model.User](ur.collection, filter)
+	results, err := FindMany[*model.User](ctx, ur.collection, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"happyAssistant/internal/model"
+	"happyAssistant/internal/repository"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxPushRetryInterval 推送重试间隔的上限
+const maxPushRetryInterval = 5 * time.Minute
+
+// PushDeliverer 将推送发送给用户的在线连接，返回是否至少有一个连接接收
+type PushDeliverer func(msg *model.PushMessage) bool
+
+// pushStore 推送的持久化存储，由 repository.PushRepository 实现
+type pushStore interface {
+	Create(ctx context.Context, msg *model.PushMessage) error
+	FindPending(ctx context.Context, userID string, now int64) ([]*model.PushMessage, error)
+	DeleteAcked(ctx context.Context, userID string, ids []string) error
+	DeleteExpired(ctx context.Context, now int64) error
+}
+
+var _ pushStore = (*repository.PushRepository)(nil)
+
+// pendingPush 等待确认的推送
+type pendingPush struct {
+	msg         *model.PushMessage
+	nextAttempt time.Time
+}
+
+// PushService 服务端推送服务
+// 推送先保存到数据库，再投递给用户的在线连接；在线期间未确认的推送按指数退避重试，
+// 用户离线或重试次数用尽后停止重试，等待用户下次登录时重新投递，确认或过期后删除
+type PushService struct {
+	pushRepo      pushStore
+	deliver       PushDeliverer
+	ttl           time.Duration
+	retryInterval time.Duration
+	maxRetries    int
+	mu            sync.Mutex
+	pending       map[string]*pendingPush
+	lastSweep     time.Time
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewPushService 创建推送服务实例，并启动重试协程
+func NewPushService(deliver PushDeliverer, ttl, retryInterval time.Duration, maxRetries int) *PushService {
+	repoManager := repository.GetRepositoryManager()
+	ps := &PushService{
+		pushRepo:      repoManager.GetPushRepository(),
+		deliver:       deliver,
+		ttl:           ttl,
+		retryInterval: retryInterval,
+		maxRetries:    maxRetries,
+		pending:       make(map[string]*pendingPush),
+		lastSweep:     time.Now(),
+		done:          make(chan struct{}),
+	}
+	go ps.retryLoop()
+	return ps
+}
+
+// Push 向用户发送推送，推送保存成功后返回，用户不在线时等待下次登录投递
+func (ps *PushService) Push(ctx context.Context, userID, kind string, data []byte) (*model.PushMessage, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	msg := &model.PushMessage{
+		Id:        hex.EncodeToString(buf),
+		UserId:    userID,
+		Kind:      kind,
+		Data:      data,
+		CreatedAt: now.Unix(),
+		ExpireAt:  now.Add(ps.ttl).Unix(),
+	}
+	if err := ps.pushRepo.Create(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to save push: %w", err)
+	}
+	ps.attempt([]*model.PushMessage{msg})
+	return msg, nil
+}
+
+// DeliverPending 投递用户未确认的推送，用户登录或携带会话令牌重连后调用
+func (ps *PushService) DeliverPending(ctx context.Context, userID string) error {
+	msgs, err := ps.pushRepo.FindPending(ctx, userID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to find pending pushes: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	// 已在重试中的推送沿用原有的重试进度
+	ps.mu.Lock()
+	fresh := msgs[:0]
+	for _, msg := range msgs {
+		if _, ok := ps.pending[msg.Id]; !ok {
+			fresh = append(fresh, msg)
+		}
+	}
+	ps.mu.Unlock()
+	log.Infof("Delivering %d pending pushes to user %s", len(fresh), userID)
+	ps.attempt(fresh)
+	return nil
+}
+
+// Ack 确认用户已收到的推送，返回本次确认的数量
+func (ps *PushService) Ack(ctx context.Context, userID string, ids []string) (int, error) {
+	ps.mu.Lock()
+	acked := 0
+	for _, id := range ids {
+		if p, ok := ps.pending[id]; ok && p.msg.UserId == userID {
+			delete(ps.pending, id)
+			acked++
+		}
+	}
+	ps.mu.Unlock()
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := ps.pushRepo.DeleteAcked(ctx, userID, ids); err != nil {
+		return acked, fmt.Errorf("failed to delete acked pushes: %w", err)
+	}
+	return acked, nil
+}
+
+// Close 停止重试协程
+func (ps *PushService) Close() {
+	ps.closeOnce.Do(func() {
+		close(ps.done)
+	})
+}
+
+// attempt 投递推送，投递成功且未超过重试次数时安排下次重试，否则等待下次登录
+// 投递前先登记为等待确认，投递期间收到的确认会移除登记，之后不再重新登记
+func (ps *PushService) attempt(msgs []*model.PushMessage) {
+	for _, msg := range msgs {
+		ps.mu.Lock()
+		msg.Attempts++
+		p := &pendingPush{msg: msg, nextAttempt: time.Now().Add(ps.backoff(msg.Attempts))}
+		ps.pending[msg.Id] = p
+		ps.mu.Unlock()
+
+		delivered := ps.deliver(msg)
+
+		ps.mu.Lock()
+		if ps.pending[msg.Id] == p && (!delivered || int(msg.Attempts) > ps.maxRetries) {
+			delete(ps.pending, msg.Id)
+		}
+		ps.mu.Unlock()
+	}
+}
+
+// backoff 第 attempts 次投递后的重试间隔
+func (ps *PushService) backoff(attempts int32) time.Duration {
+	interval := ps.retryInterval
+	for i := int32(1); i < attempts && interval < maxPushRetryInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, maxPushRetryInterval)
+}
+
+// retryLoop 定期重试到期的推送，并清理过期推送
+func (ps *PushService) retryLoop() {
+	ticker := time.NewTicker(max(ps.retryInterval/2, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ps.done:
+			return
+		case now := <-ticker.C:
+			ps.attempt(ps.due(now))
+			ps.sweep(now)
+		}
+	}
+}
+
+// due 取出到期需要重试的推送，已过期的推送直接丢弃
+func (ps *PushService) due(now time.Time) []*model.PushMessage {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	var msgs []*model.PushMessage
+	for id, p := range ps.pending {
+		if now.Unix() >= p.msg.ExpireAt {
+			delete(ps.pending, id)
+			continue
+		}
+		if !now.Before(p.nextAttempt) {
+			msgs = append(msgs, p.msg)
+		}
+	}
+	return msgs
+}
+
+// sweep 按间隔删除数据库中的过期推送
+func (ps *PushService) sweep(now time.Time) {
+	if now.Sub(ps.lastSweep) < sessionSweepInterval {
+		return
+	}
+	ps.lastSweep = now
+	if err := ps.pushRepo.DeleteExpired(context.Background(), now.Unix()); err != nil {
+		log.Errorf("Failed to delete expired pushes: %v", err)
+	}
+}
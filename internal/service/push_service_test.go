@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"happyAssistant/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePushStore 内存中的推送存储
+type fakePushStore struct {
+	mu    sync.Mutex
+	msgs  map[string]*model.PushMessage
+	acked []string
+}
+
+func newFakePushStore() *fakePushStore {
+	return &fakePushStore{msgs: make(map[string]*model.PushMessage)}
+}
+
+func (s *fakePushStore) Create(_ context.Context, msg *model.PushMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs[msg.Id] = msg
+	return nil
+}
+
+func (s *fakePushStore) FindPending(_ context.Context, userID string, now int64) ([]*model.PushMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []*model.PushMessage
+	for _, msg := range s.msgs {
+		if msg.UserId == userID && msg.ExpireAt > now {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (s *fakePushStore) DeleteAcked(_ context.Context, userID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if msg, ok := s.msgs[id]; ok && msg.UserId == userID {
+			delete(s.msgs, id)
+			s.acked = append(s.acked, id)
+		}
+	}
+	return nil
+}
+
+func (s *fakePushStore) DeleteExpired(_ context.Context, now int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, msg := range s.msgs {
+		if msg.ExpireAt <= now {
+			delete(s.msgs, id)
+		}
+	}
+	return nil
+}
+
+// newTestPushService 创建不启动重试协程的推送服务，重试由测试调用 due / attempt 驱动
+func newTestPushService(store pushStore, deliver PushDeliverer, maxRetries int) *PushService {
+	return &PushService{
+		pushRepo:      store,
+		deliver:       deliver,
+		ttl:           time.Hour,
+		retryInterval: time.Second,
+		maxRetries:    maxRetries,
+		pending:       make(map[string]*pendingPush),
+		lastSweep:     time.Now(),
+		done:          make(chan struct{}),
+	}
+}
+
+func TestPushBackoff(t *testing.T) {
+	ps := newTestPushService(newFakePushStore(), nil, 0)
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 9, want: 256 * time.Second},
+		{attempts: 10, want: maxPushRetryInterval},
+		{attempts: 100, want: maxPushRetryInterval},
+	}
+	for _, tt := range tests {
+		if got := ps.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestPushRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		online       bool
+		maxRetries   int
+		wantAttempts int32 // 推送及所有到期重试完成后的投递次数
+	}{
+		{name: "offline", online: false, maxRetries: 3, wantAttempts: 1},
+		{name: "no retries", online: true, maxRetries: 0, wantAttempts: 1},
+		{name: "retries exhausted", online: true, maxRetries: 3, wantAttempts: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delivered int
+			ps := newTestPushService(newFakePushStore(), func(*model.PushMessage) bool {
+				delivered++
+				return tt.online
+			}, tt.maxRetries)
+
+			msg, err := ps.Push(context.Background(), "u1", "notice", []byte("hello"))
+			if err != nil {
+				t.Fatalf("Push: %v", err)
+			}
+			// 每轮跳过当前的退避间隔，直到不再有待重试的推送
+			now := time.Now()
+			for round := 0; round < 10; round++ {
+				now = now.Add(maxPushRetryInterval)
+				due := ps.due(now)
+				if len(due) == 0 {
+					break
+				}
+				ps.attempt(due)
+			}
+			if msg.Attempts != tt.wantAttempts || delivered != int(tt.wantAttempts) {
+				t.Errorf("attempts = %d, delivered = %d, want %d", msg.Attempts, delivered, tt.wantAttempts)
+			}
+			if len(ps.pending) != 0 {
+				t.Errorf("pending = %d, want 0", len(ps.pending))
+			}
+		})
+	}
+}
+
+func TestPushDueWaitsForBackoff(t *testing.T) {
+	ps := newTestPushService(newFakePushStore(), func(*model.PushMessage) bool { return true }, 3)
+	if _, err := ps.Push(context.Background(), "u1", "notice", nil); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	now := time.Now()
+	if due := ps.due(now); len(due) != 0 {
+		t.Errorf("due before backoff = %d, want 0", len(due))
+	}
+	if due := ps.due(now.Add(time.Second)); len(due) != 1 {
+		t.Errorf("due after backoff = %d, want 1", len(due))
+	}
+	// 过期的推送不再重试
+	if due := ps.due(now.Add(2 * time.Hour)); len(due) != 0 || len(ps.pending) != 0 {
+		t.Errorf("due after expiry = %d, pending = %d, want 0, 0", len(due), len(ps.pending))
+	}
+}
+
+func TestPushAck(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		wantAcked int
+		wantLeft  int
+	}{
+		{name: "owner", userID: "u1", wantAcked: 1, wantLeft: 0},
+		{name: "other user", userID: "u2", wantAcked: 0, wantLeft: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakePushStore()
+			ps := newTestPushService(store, func(*model.PushMessage) bool { return true }, 3)
+			msg, err := ps.Push(context.Background(), "u1", "notice", nil)
+			if err != nil {
+				t.Fatalf("Push: %v", err)
+			}
+			acked, err := ps.Ack(context.Background(), tt.userID, []string{msg.Id})
+			if err != nil {
+				t.Fatalf("Ack: %v", err)
+			}
+			if acked != tt.wantAcked || len(ps.pending) != tt.wantLeft || len(store.msgs) != tt.wantLeft {
+				t.Errorf("acked = %d, pending = %d, stored = %d, want %d, %d, %d",
+					acked, len(ps.pending), len(store.msgs), tt.wantAcked, tt.wantLeft, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestPushDeliverPending(t *testing.T) {
+	store := newFakePushStore()
+	online := false
+	ps := newTestPushService(store, func(*model.PushMessage) bool { return online }, 3)
+	offline, err := ps.Push(context.Background(), "u1", "notice", nil)
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	// 登录后投递离线期间的推送，已在重试中的推送保持原有进度
+	online = true
+	retrying, err := ps.Push(context.Background(), "u1", "notice", nil)
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if err := ps.DeliverPending(context.Background(), "u1"); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if offline.Attempts != 2 || retrying.Attempts != 1 {
+		t.Errorf("attempts = %d, %d, want 2, 1", offline.Attempts, retrying.Attempts)
+	}
+	if len(ps.pending) != 2 {
+		t.Errorf("pending = %d, want 2", len(ps.pending))
+	}
+}
+
+func TestPushAckDuringDelivery(t *testing.T) {
+	store := newFakePushStore()
+	var ps *PushService
+	var acked int
+	ps = newTestPushService(store, func(msg *model.PushMessage) bool {
+		// 客户端在投递返回之前就已确认
+		var err error
+		if acked, err = ps.Ack(context.Background(), msg.UserId, []string{msg.Id}); err != nil {
+			t.Errorf("Ack: %v", err)
+		}
+		return true
+	}, 3)
+
+	if _, err := ps.Push(context.Background(), "u1", "notice", nil); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if acked != 1 {
+		t.Errorf("acked = %d, want 1", acked)
+	}
+	if len(ps.pending) != 0 {
+		t.Errorf("pending = %d, want 0", len(ps.pending))
+	}
+	if due := ps.due(time.Now().Add(maxPushRetryInterval)); len(due) != 0 {
+		t.Errorf("due = %d, want 0", len(due))
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// sessionSweepInterval 清理过期会话的最小间隔
+const sessionSweepInterval = 10 * time.Minute
+
+// ErrSessionInvalid 会话令牌不存在或已过期
+var ErrSessionInvalid = errors.New("session token is invalid or expired")
+
+// ClientVersion 客户端上报的版本信息
+type ClientVersion struct {
+	Platform   string // 客户端平台
+	EnvVersion string // 小程序环境
+	Version    int32  // 小程序版本，0 表示未上报
+}
+
+// Session 登录会话信息
+type Session struct {
+	Token         string
+	UserID        string
+	LabID         string
+	Permissions   uint64 // 用户在所在实验室的权限标志位
+	ClientVersion        // 登录时上报的版本信息，重连时未携带版本参数则沿用
+	ExpireAt      time.Time
+}
+
+// SessionService 会话服务
+// 登录成功后签发会话令牌，客户端重连时携带令牌即可恢复登录状态，无需重新发送登录请求。
+// 会话保存在内存中，服务重启后客户端需要重新登录
+type SessionService struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// NewSessionService 创建会话服务实例
+func NewSessionService(ttl time.Duration) *SessionService {
+	return &SessionService{
+		ttl:       ttl,
+		sessions:  make(map[string]*Session),
+		lastSweep: time.Now(),
+	}
+}
+
+// Create 为用户签发新的会话令牌，version 为登录时上报的客户端版本信息
+func (ss *SessionService) Create(userID, labID string, permissions uint64, version ClientVersion) (*Session, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	session := &Session{
+		Token:         hex.EncodeToString(buf),
+		UserID:        userID,
+		LabID:         labID,
+		Permissions:   permissions,
+		ClientVersion: version,
+		ExpireAt:      time.Now().Add(ss.ttl),
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.sweepLocked()
+	ss.sessions[session.Token] = session
+	log.Infof("Created session for user: %s", userID)
+	return session, nil
+}
+
+// Validate 校验会话令牌，返回对应的会话信息
+func (ss *SessionService) Validate(token string) (*Session, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	session, ok := ss.sessions[token]
+	if !ok {
+		return nil, ErrSessionInvalid
+	}
+	if time.Now().After(session.ExpireAt) {
+		delete(ss.sessions, token)
+		return nil, ErrSessionInvalid
+	}
+	return session, nil
+}
+
+// Revoke 注销会话令牌
+func (ss *SessionService) Revoke(token string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.sessions, token)
+}
+
+// sweepLocked 按间隔清理过期会话，调用方需持有 mu
+func (ss *SessionService) sweepLocked() {
+	now := time.Now()
+	if now.Sub(ss.lastSweep) < sessionSweepInterval {
+		return
+	}
+	ss.lastSweep = now
+	for token, session := range ss.sessions {
+		if now.After(session.ExpireAt) {
+			delete(ss.sessions, token)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"happyAssistant/internal/config"
+	"hash"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// 分片传输错误
+var (
+	ErrTransferNotFound   = errors.New("transfer not found or expired")
+	ErrTransferTooLarge   = errors.New("transfer size exceeds limit")
+	ErrTransferChecksum   = errors.New("checksum mismatch")
+	ErrTransferOffset     = errors.New("unexpected chunk offset")
+	ErrTransferChunkSize  = errors.New("chunk size exceeds limit")
+	ErrTransferIncomplete = errors.New("transfer is incomplete")
+	ErrTransferLimit      = errors.New("transfer limit exceeded")
+)
+
+// Transfer 分片传输信息，服务返回的是调用时的快照
+type Transfer struct {
+	ID          string
+	UserID      string
+	FileName    string
+	ContentType string
+	Purpose     string
+	TotalSize   int64
+	SHA256      string // 客户端声明的文件 SHA-256，完成后为实际的 SHA-256
+	Received    int64  // 已接收的字节数
+	Path        string // 服务端临时文件路径
+	ExpireAt    time.Time
+	Completed   bool
+}
+
+// transfer 服务内部的传输状态
+// mu 保护分片写入与进度，写入文件期间持有，不同传输的写入互不阻塞；expireAt 由 TransferService.mu 保护
+type transfer struct {
+	mu       sync.Mutex
+	info     Transfer  // ExpireAt 不在此维护，快照时从 expireAt 填充
+	hash     hash.Hash // 已接收数据的 SHA-256，分片按顺序写入时增量计算
+	expireAt time.Time
+}
+
+// TransferService 分片传输服务
+// 分片按顺序写入临时文件，客户端断线重连后通过传输ID续传；
+// 未完成的传输超过有效期后删除。传输状态保存在内存中，服务重启后需要重新上传。
+// 锁顺序：单个传输的 mu 在 TransferService.mu 之前获取，持有 TransferService.mu 时不等待传输的 mu
+type TransferService struct {
+	dir              string
+	maxSize          int64
+	chunkSize        int
+	ttl              time.Duration
+	maxActivePerUser int   // 单个用户未完成的传输数上限，<= 0 表示不限制
+	maxBytesPerUser  int64 // 单个用户所有传输声明的总字节数上限，<= 0 表示不限制
+	maxTotalBytes    int64 // 所有传输声明的总字节数上限，<= 0 表示不限制
+	mu               sync.Mutex
+	transfers        map[string]*transfer
+	lastSweep        time.Time
+}
+
+// NewTransferService 创建分片传输服务实例
+func NewTransferService(cfg config.TransferConfig) *TransferService {
+	return &TransferService{
+		dir:              cfg.Dir,
+		maxSize:          cfg.MaxSize,
+		chunkSize:        cfg.ChunkSize,
+		ttl:              cfg.TTL,
+		maxActivePerUser: cfg.MaxActivePerUser,
+		maxBytesPerUser:  cfg.MaxBytesPerUser,
+		maxTotalBytes:    cfg.MaxTotalBytes,
+		transfers:        make(map[string]*transfer),
+		lastSweep:        time.Now(),
+	}
+}
+
+// ChunkSize 单个分片的最大字节数
+func (ts *TransferService) ChunkSize() int {
+	return ts.chunkSize
+}
+
+// Begin 开始新的传输，transferID 不为空时续传该用户未完成的传输
+// 新传输超过用户或服务的并发数、总字节数上限时返回 ErrTransferLimit
+func (ts *TransferService) Begin(userID, transferID, fileName, contentType, purpose string, totalSize int64, sha string) (*Transfer, error) {
+	if transferID != "" {
+		t, err := ts.get(userID, transferID)
+		if err != nil {
+			return nil, err
+		}
+		ts.touch(t)
+		info := ts.snapshot(t)
+		log.Infof("Resuming transfer %s at offset %d", info.ID, info.Received)
+		return info, nil
+	}
+
+	if totalSize <= 0 || totalSize > ts.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes, max %d", ErrTransferTooLarge, totalSize, ts.maxSize)
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(buf)
+	t := &transfer{
+		info: Transfer{
+			ID:          id,
+			UserID:      userID,
+			FileName:    filepath.Base(fileName),
+			ContentType: contentType,
+			Purpose:     purpose,
+			TotalSize:   totalSize,
+			SHA256:      sha,
+			Path:        filepath.Join(ts.dir, id+".part"),
+		},
+		hash:     sha256.New(),
+		expireAt: time.Now().Add(ts.ttl),
+	}
+
+	// 先占用额度再创建文件，避免并发的 Begin 同时通过检查
+	ts.mu.Lock()
+	ts.sweepLocked()
+	if err := ts.checkLimitsLocked(userID, totalSize); err != nil {
+		ts.mu.Unlock()
+		return nil, err
+	}
+	ts.transfers[id] = t
+	ts.mu.Unlock()
+
+	if err := createTransferFile(ts.dir, t.info.Path); err != nil {
+		ts.mu.Lock()
+		delete(ts.transfers, id)
+		ts.mu.Unlock()
+		return nil, err
+	}
+	log.Infof("Began transfer %s for user %s: %s (%d bytes)", id, userID, t.info.FileName, totalSize)
+	return ts.snapshot(t), nil
+}
+
+// checkLimitsLocked 检查新传输是否超过上限，调用方需持有 mu
+// 字节数按声明的 TotalSize 计算，已完成但尚未删除的传输仍占用磁盘，同样计入
+func (ts *TransferService) checkLimitsLocked(userID string, totalSize int64) error {
+	active := 0
+	userBytes, totalBytes := totalSize, totalSize
+	for _, t := range ts.transfers {
+		// TotalSize 与 UserID 创建后不再修改，无需持有传输的 mu
+		totalBytes += t.info.TotalSize
+		if t.info.UserID != userID {
+			continue
+		}
+		userBytes += t.info.TotalSize
+		if !ts.completed(t) {
+			active++
+		}
+	}
+	switch {
+	case ts.maxActivePerUser > 0 && active >= ts.maxActivePerUser:
+		return fmt.Errorf("%w: %d active transfers, max %d", ErrTransferLimit, active, ts.maxActivePerUser)
+	case ts.maxBytesPerUser > 0 && userBytes > ts.maxBytesPerUser:
+		return fmt.Errorf("%w: user declared %d bytes, max %d", ErrTransferLimit, userBytes, ts.maxBytesPerUser)
+	case ts.maxTotalBytes > 0 && totalBytes > ts.maxTotalBytes:
+		return fmt.Errorf("%w: declared %d bytes in total, max %d", ErrTransferLimit, totalBytes, ts.maxTotalBytes)
+	}
+	return nil
+}
+
+// completed 传输是否已完成，只用于统计额度，不等待正在写入的分片
+func (ts *TransferService) completed(t *transfer) bool {
+	if !t.mu.TryLock() {
+		// 正在写入分片或校验，按未完成计算
+		return false
+	}
+	defer t.mu.Unlock()
+	return t.info.Completed
+}
+
+// createTransferFile 创建空的临时文件
+func createTransferFile(dir, path string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create transfer dir: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to create transfer file: %w", err)
+	}
+	return nil
+}
+
+// WriteChunk 写入分片，返回已接收的字节数
+// 重复发送已接收过的分片（例如重连后重发）直接返回当前进度
+func (ts *TransferService) WriteChunk(userID, transferID string, offset int64, data []byte, checksum uint32) (int64, error) {
+	t, err := ts.get(userID, transferID)
+	if err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(data) > ts.chunkSize {
+		return t.info.Received, fmt.Errorf("%w: %d bytes, max %d", ErrTransferChunkSize, len(data), ts.chunkSize)
+	}
+	if crc32.ChecksumIEEE(data) != checksum {
+		return t.info.Received, ErrTransferChecksum
+	}
+	if offset+int64(len(data)) <= t.info.Received {
+		return t.info.Received, nil
+	}
+	if offset != t.info.Received {
+		return t.info.Received, fmt.Errorf("%w: got %d, want %d", ErrTransferOffset, offset, t.info.Received)
+	}
+	if t.info.Received+int64(len(data)) > t.info.TotalSize {
+		return t.info.Received, fmt.Errorf("%w: chunk exceeds declared size %d", ErrTransferTooLarge, t.info.TotalSize)
+	}
+
+	file, err := os.OpenFile(t.info.Path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return t.info.Received, fmt.Errorf("failed to open transfer file: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// 写入失败时截断到已确认的进度，客户端可重发该分片
+		_ = os.Truncate(t.info.Path, t.info.Received)
+		return t.info.Received, fmt.Errorf("failed to write transfer file: %w", err)
+	}
+
+	t.hash.Write(data)
+	t.info.Received += int64(len(data))
+	ts.touch(t)
+	return t.info.Received, nil
+}
+
+// Finish 完成传输，校验文件大小与 SHA-256
+func (ts *TransferService) Finish(userID, transferID string) (*Transfer, error) {
+	t, err := ts.get(userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	if t.info.Completed {
+		t.mu.Unlock()
+		return ts.snapshot(t), nil
+	}
+	if received := t.info.Received; received != t.info.TotalSize {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%w: received %d of %d bytes", ErrTransferIncomplete, received, t.info.TotalSize)
+	}
+	sum := hex.EncodeToString(t.hash.Sum(nil))
+	if t.info.SHA256 != "" && t.info.SHA256 != sum {
+		t.mu.Unlock()
+		ts.remove(t)
+		return nil, fmt.Errorf("%w: file sha256 %s", ErrTransferChecksum, sum)
+	}
+	t.info.SHA256 = sum
+	t.info.Completed = true
+	t.mu.Unlock()
+
+	ts.touch(t)
+	info := ts.snapshot(t)
+	log.Infof("Finished transfer %s: %s (%d bytes)", info.ID, info.FileName, info.TotalSize)
+	return info, nil
+}
+
+// Get 获取已完成的传输，供引用上传文件的业务使用
+func (ts *TransferService) Get(userID, transferID string) (*Transfer, error) {
+	t, err := ts.get(userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	info := ts.snapshot(t)
+	if !info.Completed {
+		return nil, ErrTransferIncomplete
+	}
+	return info, nil
+}
+
+// Remove 删除传输及其临时文件，业务处理完上传文件后调用
+func (ts *TransferService) Remove(transferID string) {
+	ts.mu.Lock()
+	t, ok := ts.transfers[transferID]
+	ts.mu.Unlock()
+	if ok {
+		ts.remove(t)
+	}
+}
+
+// get 获取用户未过期的传输
+func (ts *TransferService) get(userID, transferID string) (*transfer, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.transfers[transferID]
+	if !ok || t.info.UserID != userID {
+		return nil, ErrTransferNotFound
+	}
+	if time.Now().After(t.expireAt) {
+		ts.removeLocked(t)
+		return nil, ErrTransferNotFound
+	}
+	return t, nil
+}
+
+// touch 延长传输的有效期
+func (ts *TransferService) touch(t *transfer) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t.expireAt = time.Now().Add(ts.ttl)
+}
+
+// snapshot 复制传输的当前状态返回给调用方
+func (ts *TransferService) snapshot(t *transfer) *Transfer {
+	t.mu.Lock()
+	info := t.info
+	t.mu.Unlock()
+	ts.mu.Lock()
+	info.ExpireAt = t.expireAt
+	ts.mu.Unlock()
+	return &info
+}
+
+// remove 删除传输及其临时文件
+func (ts *TransferService) remove(t *transfer) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.removeLocked(t)
+}
+
+// removeLocked 删除传输及其临时文件，调用方需持有 mu
+// 正在写入的分片会写入已删除的文件，之后的操作返回 ErrTransferNotFound
+func (ts *TransferService) removeLocked(t *transfer) {
+	if ts.transfers[t.info.ID] != t {
+		return
+	}
+	delete(ts.transfers, t.info.ID)
+	if err := os.Remove(t.info.Path); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Failed to remove transfer file %s: %v", t.info.Path, err)
+	}
+}
+
+// sweepLocked 按间隔清理过期传输，调用方需持有 mu
+func (ts *TransferService) sweepLocked() {
+	now := time.Now()
+	if now.Sub(ts.lastSweep) < sessionSweepInterval {
+		return
+	}
+	ts.lastSweep = now
+	for _, t := range ts.transfers {
+		if now.After(t.expireAt) {
+			ts.removeLocked(t)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"happyAssistant/internal/config"
+	"hash/crc32"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestTransferService(t *testing.T, cfg config.TransferConfig) *TransferService {
+	t.Helper()
+	cfg.Dir = t.TempDir()
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = 1 << 20
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = 4
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Hour
+	}
+	return NewTransferService(cfg)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTransferWriteChunk(t *testing.T) {
+	type chunk struct {
+		offset       int64
+		data         string
+		badChecksum  bool
+		wantReceived int64
+		wantErr      error
+	}
+	tests := []struct {
+		name   string
+		size   int64
+		chunks []chunk
+	}{
+		{name: "in order", size: 8, chunks: []chunk{
+			{offset: 0, data: "abcd", wantReceived: 4},
+			{offset: 4, data: "efgh", wantReceived: 8},
+		}},
+		{name: "duplicate chunk is acknowledged", size: 8, chunks: []chunk{
+			{offset: 0, data: "abcd", wantReceived: 4},
+			{offset: 0, data: "abcd", wantReceived: 4},
+			{offset: 4, data: "efgh", wantReceived: 8},
+		}},
+		{name: "gap", size: 8, chunks: []chunk{
+			{offset: 0, data: "abcd", wantReceived: 4},
+			{offset: 6, data: "gh", wantReceived: 4, wantErr: ErrTransferOffset},
+		}},
+		{name: "overlapping chunk", size: 8, chunks: []chunk{
+			{offset: 0, data: "abcd", wantReceived: 4},
+			{offset: 2, data: "cdef", wantReceived: 4, wantErr: ErrTransferOffset},
+		}},
+		{name: "checksum mismatch", size: 8, chunks: []chunk{
+			{offset: 0, data: "abcd", badChecksum: true, wantReceived: 0, wantErr: ErrTransferChecksum},
+			{offset: 0, data: "abcd", wantReceived: 4},
+		}},
+		{name: "chunk too large", size: 8, chunks: []chunk{
+			{offset: 0, data: "abcde", wantReceived: 0, wantErr: ErrTransferChunkSize},
+		}},
+		{name: "beyond declared size", size: 6, chunks: []chunk{
+			{offset: 0, data: "abcd", wantReceived: 4},
+			{offset: 4, data: "efgh", wantReceived: 4, wantErr: ErrTransferTooLarge},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestTransferService(t, config.TransferConfig{})
+			transfer, err := ts.Begin("u1", "", "a.txt", "text/plain", "test", tt.size, "")
+			if err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+			for i, c := range tt.chunks {
+				checksum := crc32.ChecksumIEEE([]byte(c.data))
+				if c.badChecksum {
+					checksum++
+				}
+				received, err := ts.WriteChunk("u1", transfer.ID, c.offset, []byte(c.data), checksum)
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("chunk %d err = %v, want %v", i, err, c.wantErr)
+				}
+				if received != c.wantReceived {
+					t.Errorf("chunk %d received = %d, want %d", i, received, c.wantReceived)
+				}
+			}
+		})
+	}
+}
+
+func TestTransferFinish(t *testing.T) {
+	tests := []struct {
+		name      string
+		declared  string // 客户端声明的 SHA-256
+		upload    string
+		size      int64
+		wantErr   error
+		wantGone  bool // 失败后传输被删除
+		wantSHA   string
+		finishTwo bool
+	}{
+		{name: "checksum matches", declared: sha256Hex("abcdefgh"), upload: "abcdefgh", size: 8, wantSHA: sha256Hex("abcdefgh"), finishTwo: true},
+		{name: "no declared checksum", upload: "abcdefgh", size: 8, wantSHA: sha256Hex("abcdefgh")},
+		{name: "checksum mismatch", declared: sha256Hex("other"), upload: "abcdefgh", size: 8, wantErr: ErrTransferChecksum, wantGone: true},
+		{name: "incomplete", upload: "abcd", size: 8, wantErr: ErrTransferIncomplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestTransferService(t, config.TransferConfig{})
+			transfer, err := ts.Begin("u1", "", "a.txt", "", "", tt.size, tt.declared)
+			if err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+			for offset := 0; offset < len(tt.upload); offset += 4 {
+				data := []byte(tt.upload[offset:min(offset+4, len(tt.upload))])
+				if _, err := ts.WriteChunk("u1", transfer.ID, int64(offset), data, crc32.ChecksumIEEE(data)); err != nil {
+					t.Fatalf("WriteChunk: %v", err)
+				}
+			}
+
+			finished, err := ts.Finish("u1", transfer.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Finish err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantGone {
+				if _, err := os.Stat(transfer.Path); !os.IsNotExist(err) {
+					t.Errorf("temp file still exists after failed finish: %v", err)
+				}
+				if _, err := ts.Finish("u1", transfer.ID); !errors.Is(err, ErrTransferNotFound) {
+					t.Errorf("Finish after removal err = %v, want ErrTransferNotFound", err)
+				}
+			}
+			if err != nil {
+				return
+			}
+			if finished.SHA256 != tt.wantSHA || !finished.Completed {
+				t.Errorf("finished = %+v, want completed with sha %s", finished, tt.wantSHA)
+			}
+			content, err := os.ReadFile(finished.Path)
+			if err != nil || string(content) != tt.upload {
+				t.Errorf("file content = %q, %v, want %q", content, err, tt.upload)
+			}
+			if tt.finishTwo {
+				if again, err := ts.Finish("u1", transfer.ID); err != nil || again.SHA256 != tt.wantSHA {
+					t.Errorf("second Finish = %+v, %v", again, err)
+				}
+			}
+			if got, err := ts.Get("u1", transfer.ID); err != nil || got.ID != transfer.ID {
+				t.Errorf("Get = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestTransferResumeAndOwnership(t *testing.T) {
+	ts := newTestTransferService(t, config.TransferConfig{})
+	transfer, err := ts.Begin("u1", "", "a.txt", "", "", 8, "")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := ts.WriteChunk("u1", transfer.ID, 0, []byte("abcd"), crc32.ChecksumIEEE([]byte("abcd"))); err != nil {
+		t.Fatalf("WriteChunk: %v", err)
+	}
+
+	resumed, err := ts.Begin("u1", transfer.ID, "", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if resumed.Received != 4 {
+		t.Errorf("resumed at %d, want 4", resumed.Received)
+	}
+	if _, err := ts.Begin("u2", transfer.ID, "", "", "", 0, ""); !errors.Is(err, ErrTransferNotFound) {
+		t.Errorf("resume by another user err = %v, want ErrTransferNotFound", err)
+	}
+	if _, err := ts.WriteChunk("u2", transfer.ID, 4, []byte("efgh"), crc32.ChecksumIEEE([]byte("efgh"))); !errors.Is(err, ErrTransferNotFound) {
+		t.Errorf("write by another user err = %v, want ErrTransferNotFound", err)
+	}
+}
+
+func TestTransferLimits(t *testing.T) {
+	type begin struct {
+		userID  string
+		size    int64
+		wantErr error
+	}
+	tests := []struct {
+		name   string
+		cfg    config.TransferConfig
+		begins []begin
+	}{
+		{name: "active per user", cfg: config.TransferConfig{MaxActivePerUser: 2}, begins: []begin{
+			{userID: "u1", size: 1},
+			{userID: "u1", size: 1},
+			{userID: "u1", size: 1, wantErr: ErrTransferLimit},
+			{userID: "u2", size: 1},
+		}},
+		{name: "bytes per user", cfg: config.TransferConfig{MaxBytesPerUser: 10}, begins: []begin{
+			{userID: "u1", size: 6},
+			{userID: "u1", size: 5, wantErr: ErrTransferLimit},
+			{userID: "u1", size: 4},
+			{userID: "u2", size: 10},
+		}},
+		{name: "total bytes", cfg: config.TransferConfig{MaxTotalBytes: 10}, begins: []begin{
+			{userID: "u1", size: 6},
+			{userID: "u2", size: 5, wantErr: ErrTransferLimit},
+			{userID: "u2", size: 4},
+		}},
+		{name: "size above max", cfg: config.TransferConfig{MaxSize: 8}, begins: []begin{
+			{userID: "u1", size: 9, wantErr: ErrTransferTooLarge},
+			{userID: "u1", size: 0, wantErr: ErrTransferTooLarge},
+		}},
+		{name: "unlimited", cfg: config.TransferConfig{MaxActivePerUser: -1, MaxBytesPerUser: -1, MaxTotalBytes: -1}, begins: []begin{
+			{userID: "u1", size: 1 << 20},
+			{userID: "u1", size: 1 << 20},
+			{userID: "u1", size: 1 << 20},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestTransferService(t, tt.cfg)
+			for i, b := range tt.begins {
+				_, err := ts.Begin(b.userID, "", "a.txt", "", "", b.size, "")
+				if !errors.Is(err, b.wantErr) {
+					t.Fatalf("begin %d err = %v, want %v", i, err, b.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestTransferLimitReleasedAfterFinishAndRemove(t *testing.T) {
+	ts := newTestTransferService(t, config.TransferConfig{MaxActivePerUser: 1, MaxBytesPerUser: 4})
+	transfer, err := ts.Begin("u1", "", "a.txt", "", "", 4, "")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := ts.WriteChunk("u1", transfer.ID, 0, []byte("abcd"), crc32.ChecksumIEEE([]byte("abcd"))); err != nil {
+		t.Fatalf("WriteChunk: %v", err)
+	}
+	if _, err := ts.Finish("u1", transfer.ID); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	// 已完成的传输不再计入并发数，但仍占用字节额度，直到业务删除
+	if _, err := ts.Begin("u1", "", "b.txt", "", "", 1, ""); !errors.Is(err, ErrTransferLimit) {
+		t.Fatalf("Begin before Remove err = %v, want ErrTransferLimit", err)
+	}
+	ts.Remove(transfer.ID)
+	if _, err := ts.Begin("u1", "", "b.txt", "", "", 4, ""); err != nil {
+		t.Fatalf("Begin after Remove: %v", err)
+	}
+}
+
+func TestTransferConcurrentWrites(t *testing.T) {
+	ts := newTestTransferService(t, config.TransferConfig{})
+	const transfers, chunks = 8, 16
+	var wg sync.WaitGroup
+	for i := 0; i < transfers; i++ {
+		transfer, err := ts.Begin("u1", "", "a.txt", "", "", chunks*4, "")
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := []byte("abcd")
+			for c := 0; c < chunks; c++ {
+				if _, err := ts.WriteChunk("u1", transfer.ID, int64(c*4), data, crc32.ChecksumIEEE(data)); err != nil {
+					t.Errorf("WriteChunk: %v", err)
+					return
+				}
+			}
+			if _, err := ts.Finish("u1", transfer.ID); err != nil {
+				t.Errorf("Finish: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
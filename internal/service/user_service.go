@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"happyAssistant/internal/model"
@@ -10,6 +11,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrInvalidJsCode 微信小程序登录凭证无效
+var ErrInvalidJsCode = errors.New("invalid js_code")
+
 // UserService 用户服务
 // 处理用户相关的业务逻辑，如登录、注册、用户信息管理等
 type UserService struct {
@@ -30,23 +34,23 @@ func NewUserService() *UserService {
 
 // Login 用户登录
 // 处理微信小程序登录，验证js_code并返回用户信息和实验室信息
-func (us *UserService) Login(jsCode string) (*model.LoginResponse, error) {
+func (us *UserService) Login(ctx context.Context, jsCode string) (*model.LoginResponse, error) {
 	log.Infof("Processing login request with js_code: %s", jsCode)
 
 	// 1. 验证js_code（这里需要调用微信API）
-	openID, _, err := us.validateWechatCode(jsCode)
+	openID, _, err := us.validateWechatCode(ctx, jsCode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate wechat code: %w", err)
 	}
 
 	// 2. 查找或创建用户
-	user, err := us.FindOrCreateUser(openID)
+	user, err := us.FindOrCreateUser(ctx, openID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find or create user: %w", err)
 	}
 
 	// 3. 获取用户默认实验室信息
-	labInfo, err := us.getUserLabInfo(user.Id)
+	labInfo, err := us.getUserLabInfo(ctx, user.Id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user lab info: %w", err)
 	}
@@ -63,14 +67,14 @@ func (us *UserService) Login(jsCode string) (*model.LoginResponse, error) {
 
 // validateWechatCode 验证微信小程序登录凭证
 // 调用微信API验证js_code并获取openid和session_key
-func (us *UserService) validateWechatCode(jsCode string) (string, string, error) {
+func (us *UserService) validateWechatCode(ctx context.Context, jsCode string) (string, string, error) {
 	// TODO: 实现微信API调用
-	// 这里需要调用微信小程序的登录API
+	// 这里需要调用微信小程序的登录API，HTTP 请求使用 ctx，客户端断开或请求超时后随之取消
 	// https://developers.weixin.qq.com/miniprogram/dev/api-backend/open-api/login/auth.code2Session.html
 
 	// 临时返回模拟数据，实际项目中需要调用微信API
 	if jsCode == "" {
-		return "", "", errors.New("invalid js_code")
+		return "", "", ErrInvalidJsCode
 	}
 
 	// 模拟微信API返回
@@ -83,9 +87,9 @@ func (us *UserService) validateWechatCode(jsCode string) (string, string, error)
 
 // findOrCreateUser 查找或创建用户
 // 根据openID查找用户，如果不存在则创建新用户
-func (us *UserService) FindOrCreateUser(openID string) (*model.User, error) {
+func (us *UserService) FindOrCreateUser(ctx context.Context, openID string) (*model.User, error) {
 	// 先尝试查找现有用户
-	user, err := us.userRepo.FindByOpenID(openID)
+	user, err := us.userRepo.FindByOpenID(ctx, openID)
 	if err == nil && user != nil {
 		log.Infof("Found existing user: %s", user.Id)
 		return user, nil
@@ -102,7 +106,7 @@ func (us *UserService) FindOrCreateUser(openID string) (*model.User, error) {
 		// 暂时使用name字段存储openID作为临时方案
 	}
 
-	err = us.userRepo.Create(newUser)
+	err = us.userRepo.Create(ctx, newUser)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -113,15 +117,15 @@ func (us *UserService) FindOrCreateUser(openID string) (*model.User, error) {
 
 // getUserLabInfo 获取用户实验室信息
 // 获取用户当前选中的实验室信息和角色信息
-func (us *UserService) getUserLabInfo(userID string) (*model.LoginLabInfo, error) {
+func (us *UserService) getUserLabInfo(ctx context.Context, userID string) (*model.LoginLabInfo, error) {
 	// 获取用户默认实验室（这里简化处理，实际可能需要从用户配置中获取）
-	lab, err := us.labRepo.GetDefaultLab()
+	lab, err := us.labRepo.GetDefaultLab(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default lab: %w", err)
 	}
 
 	// 获取实验室的所有角色
-	roles, err := us.roleRepo.GetRolesByLabID(lab.Id)
+	roles, err := us.roleRepo.GetRolesByLabID(ctx, lab.Id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab roles: %w", err)
 	}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"happyAssistant/internal/config"
+	"happyAssistant/internal/model"
+)
+
+// 默认升级提示
+const (
+	defaultForceUpdateMessage = "This version is no longer supported, please update to the latest version"
+	defaultSoftUpdateMessage  = "A new version is available"
+)
+
+// VersionService 客户端版本策略
+// 按客户端平台和小程序环境匹配版本规则，判断客户端是否需要升级
+type VersionService struct {
+	rules []config.VersionRule
+}
+
+// NewVersionService 创建客户端版本策略服务实例
+func NewVersionService(cfg config.VersionConfig) *VersionService {
+	return &VersionService{rules: cfg.Rules}
+}
+
+// Check 检查客户端版本，无需升级时返回 nil
+// 低于最低支持版本时返回 force 为 true 的升级信息，低于最新版本时返回建议升级的信息
+func (vs *VersionService) Check(platform, envVersion string, version int32) *model.AppUpdateInfo {
+	rule := vs.match(platform, envVersion)
+	if rule == nil {
+		return nil
+	}
+	info := &model.AppUpdateInfo{
+		MinVersion:    rule.MinVersion,
+		LatestVersion: rule.LatestVersion,
+		Message:       rule.Message,
+	}
+	switch {
+	case version < rule.MinVersion:
+		info.Force = true
+		if info.Message == "" {
+			info.Message = defaultForceUpdateMessage
+		}
+	case version < rule.LatestVersion:
+		if info.Message == "" {
+			info.Message = defaultSoftUpdateMessage
+		}
+	default:
+		return nil
+	}
+	return info
+}
+
+// match 获取最匹配的规则：平台匹配优先于环境匹配，同等匹配程度时取配置中靠前的规则
+func (vs *VersionService) match(platform, envVersion string) *config.VersionRule {
+	var best *config.VersionRule
+	bestScore := -1
+	for i := range vs.rules {
+		rule := &vs.rules[i]
+		if (rule.Platform != "" && rule.Platform != platform) || (rule.EnvVersion != "" && rule.EnvVersion != envVersion) {
+			continue
+		}
+		score := 0
+		if rule.Platform != "" {
+			score += 2
+		}
+		if rule.EnvVersion != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,200 @@
+package wshub
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WithMaxConnections 设置最大连接数，达到上限后新的升级请求返回 503，<= 0 表示不限制
+func WithMaxConnections(max int) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.MaxConnections = max
+	}
+}
+
+// WithMaxConnectionsPerIP 设置单个客户端 IP 的最大连接数，达到上限后新的升级请求返回 429，<= 0 表示不限制
+func WithMaxConnectionsPerIP(max int) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.MaxConnectionsPerIP = max
+	}
+}
+
+// WithTrustedProxies 设置可信代理的 IP 或 CIDR，来自可信代理的请求从 X-Forwarded-For / X-Real-IP 中获取客户端真实 IP
+func WithTrustedProxies(proxies ...string) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.TrustedProxies = proxies
+	}
+}
+
+// WithIdleTimeout 设置空闲超时，超过该时间未收到业务消息（Ping/Pong 不计）的客户端会被断开，<= 0 表示不限制
+func WithIdleTimeout(timeout time.Duration) ClientOption {
+	return func(config *ClientConfig) {
+		config.idleTimeout = timeout
+	}
+}
+
+// WithActivityFilter 设置判断消息是否计入空闲检测的函数，返回 false 的消息（例如应用层心跳）不重置空闲超时，
+// 只重置读超时；未设置时所有业务消息都计入
+func WithActivityFilter(isActivity func(msg []byte) bool) ClientOption {
+	return func(config *ClientConfig) {
+		config.isActivity = isActivity
+	}
+}
+
+// admission 连接准入计数，包括正在握手的连接
+type admission struct {
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+// reserve 为新连接占用名额，超过上限时返回对应的 HTTP 状态码
+func (a *admission) reserve(ip string, maxTotal, maxPerIP int) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if maxTotal > 0 && a.total >= maxTotal {
+		return http.StatusServiceUnavailable, false
+	}
+	if maxPerIP > 0 && a.perIP[ip] >= maxPerIP {
+		return http.StatusTooManyRequests, false
+	}
+	a.total++
+	a.perIP[ip]++
+	return 0, true
+}
+
+// release 释放连接占用的名额
+func (a *admission) release(ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.total--
+	if a.perIP[ip]--; a.perIP[ip] <= 0 {
+		delete(a.perIP, ip)
+	}
+}
+
+// admit 检查连接上限，拒绝时写入 HTTP 错误响应并返回 false
+func (wsh *WebSocketHub) admit(w http.ResponseWriter, ip string) bool {
+	status, ok := wsh.admission.reserve(ip, wsh.MaxConnections, wsh.MaxConnectionsPerIP)
+	if ok {
+		return true
+	}
+	message := "too many connections"
+	if status == http.StatusTooManyRequests {
+		message = "too many connections from " + ip
+	}
+	http.Error(w, message, status)
+	if wsh.OnError != nil {
+		wsh.OnError(nil, wrapHubErr("admit", fmt.Errorf("%s", message)))
+	}
+	return false
+}
+
+// parseTrustedProxies 解析可信代理列表，支持单个 IP 和 CIDR
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// isTrustedProxy 判断 IP 是否属于可信代理
+func (wsh *WebSocketHub) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range wsh.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP 获取客户端真实 IP
+// 只有直连方是可信代理时才读取 X-Forwarded-For，从右向左跳过可信代理，第一个不可信的地址即为客户端；
+// 没有 X-Forwarded-For 时使用 X-Real-IP
+func (wsh *WebSocketHub) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !wsh.isTrustedProxy(ip) {
+		return ip
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !wsh.isTrustedProxy(hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return ip
+}
+
+// RemoteIP 获取客户端真实 IP
+func (c *Client) RemoteIP() string {
+	return c.remoteIP
+}
+
+// startIdleCheck 启动空闲检测
+func (c *Client) startIdleCheck() {
+	if c.idleTimeout <= 0 {
+		return
+	}
+	c.touch()
+	time.AfterFunc(c.idleTimeout, c.checkIdle)
+}
+
+// touch 记录最近一次收到业务消息的时间
+func (c *Client) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// checkIdle 空闲超时后断开连接，否则在剩余时间后再次检查
+func (c *Client) checkIdle() {
+	if c.isClosed() {
+		return
+	}
+	remaining := time.Until(time.Unix(0, c.lastActive.Load()).Add(c.idleTimeout))
+	if remaining > 0 {
+		time.AfterFunc(remaining, c.checkIdle)
+		return
+	}
+	c.abort(websocket.CloseGoingAway, "idle timeout")
+}
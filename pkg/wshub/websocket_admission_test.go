@@ -0,0 +1,96 @@
+package wshub
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIdleTimeoutActivityFilter(t *testing.T) {
+	const idleTimeout = 300 * time.Millisecond
+	isActivity := func(msg []byte) bool { return !bytes.Equal(msg, []byte("heartbeat")) }
+	tests := []struct {
+		name       string
+		msg        string
+		filter     func(msg []byte) bool
+		wantClosed bool
+	}{
+		{name: "heartbeat only is reaped", msg: "heartbeat", filter: isActivity, wantClosed: true},
+		{name: "business message keeps alive", msg: "request", filter: isActivity, wantClosed: false},
+		{name: "no filter counts every message", msg: "heartbeat", wantClosed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []ClientOption{WithIdleTimeout(idleTimeout), WithReadDeadline(time.Second)}
+			if tt.filter != nil {
+				opts = append(opts, WithActivityFilter(tt.filter))
+			}
+			client, peer := newTestClient(t, opts...)
+			client.Start()
+			go func() {
+				for {
+					if _, _, err := peer.ReadMessage(); err != nil {
+						return
+					}
+				}
+			}()
+
+			// 持续发送消息，读超时始终不会触发，只有空闲检测可能断开连接
+			deadline := time.Now().Add(3 * idleTimeout)
+			for time.Now().Before(deadline) && !client.isClosed() {
+				if err := peer.WriteMessage(websocket.TextMessage, []byte(tt.msg)); err != nil {
+					break
+				}
+				time.Sleep(idleTimeout / 6)
+			}
+			if got := client.isClosed(); got != tt.wantClosed {
+				t.Errorf("closed = %v, want %v", got, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	hub, err := NewHub(WithTrustedProxies("10.0.0.0/8", "192.168.1.1"))
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string // 每个元素为一个 X-Forwarded-For 头
+		realIP     string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "remote addr without port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted peer ignores headers", remoteAddr: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"}, realIP: "198.51.100.2", want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "skips trusted hops from the right", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.1, 192.168.1.1, 10.0.0.2"}, want: "198.51.100.1"},
+		// 客户端自行添加的最左侧地址不可信，取可信代理追加的第一个不可信地址
+		{name: "spoofed left-most hop", remoteAddr: "10.0.0.1:5000", forwarded: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "multiple headers", remoteAddr: "10.0.0.1:5000", forwarded: []string{"1.2.3.4", "198.51.100.1, 10.0.0.2"}, want: "198.51.100.1"},
+		{name: "empty hops", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.1, , "}, want: "198.51.100.1"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.1:5000", forwarded: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "real ip", remoteAddr: "10.0.0.1:5000", realIP: " 198.51.100.2 ", want: "198.51.100.2"},
+		{name: "forwarded takes precedence over real ip", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.1"}, realIP: "198.51.100.2", want: "198.51.100.1"},
+		{name: "trusted proxy without headers", remoteAddr: "192.168.1.1:5000", want: "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: tt.remoteAddr, Header: http.Header{}}
+			for _, forwarded := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := hub.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
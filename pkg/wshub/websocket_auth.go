@@ -0,0 +1,53 @@
+package wshub
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Authenticator 握手鉴权函数，在连接升级之前调用
+// 返回的 values 会写入新客户端的 Context；返回错误时拒绝升级，
+// 错误为 *HTTPError 时使用其状态码，否则返回 401
+type Authenticator func(r *http.Request) (values map[string]interface{}, err error)
+
+// HTTPError 携带 HTTP 状态码的握手错误
+type HTTPError struct {
+	Status  int
+	Message string
+}
+
+// NewHTTPError 创建握手错误
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// SetAuthenticator 设置握手鉴权函数
+func (wsh *WebSocketHub) SetAuthenticator(authenticator Authenticator) {
+	wsh.authenticator = authenticator
+}
+
+// authenticate 执行握手鉴权，拒绝时写入 HTTP 错误响应并返回 false
+func (wsh *WebSocketHub) authenticate(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	if wsh.authenticator == nil {
+		return nil, true
+	}
+	values, err := wsh.authenticator(r)
+	if err == nil {
+		return values, true
+	}
+
+	status := http.StatusUnauthorized
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Status
+	}
+	http.Error(w, err.Error(), status)
+	if wsh.OnError != nil {
+		wsh.OnError(nil, wrapHubErr("authenticate", err))
+	}
+	return nil, false
+}
@@ -2,16 +2,42 @@ package wshub
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 type Message struct {
-	Type int
-	Data []byte
+	Type     int
+	Data     []byte
+	Priority Priority
+	seq      uint64 // 会话恢复序号，补发的数据帧不为 0
+	noWait   bool   // Hub 群发的消息，BlockWithTimeout 策略下在后台等待队列空位，不阻塞群发
+}
+
+// Priority 消息发送优先级，决定消息进入哪个发送队列
+type Priority int
+
+const (
+	// PriorityDefault 未指定：通过 IClient 的 SendText / SendBinary 发送，*Client 按 PriorityHigh 处理；
+	// 通过 Hub 的 SendTo / Broadcast / Publish 群发给 *Client 时按 PriorityBulk 处理
+	PriorityDefault Priority = iota
+	PriorityHigh             // 控制与请求响应消息，优先发送
+	PriorityBulk             // 批量推送消息，例如成员列表、广播
+)
+
+// NewBinaryMessage 创建二进制消息
+func NewBinaryMessage(data []byte) *Message {
+	return &Message{Type: websocket.BinaryMessage, Data: data}
+}
+
+// NewTextMessage 创建文本消息
+func NewTextMessage(data []byte) *Message {
+	return &Message{Type: websocket.TextMessage, Data: data}
 }
 
 // IClient WebSocket客户端接口
@@ -19,6 +45,7 @@ type IClient interface {
 	SendText(msg []byte) error
 	SendBinary(msg []byte) error
 	Close()
+	CloseWithReason(code int, text string)
 	GetBaseClient() *Client
 	// Context相关方法
 	SetContextValue(key string, value interface{})
@@ -29,37 +56,96 @@ type IClient interface {
 }
 
 type Client struct {
-	OnMessage  func(client IClient, msg []byte)
-	OnError    func(client IClient, err error)
-	OnClose    func(client IClient)
-	conn       *websocket.Conn
-	sendBuffer chan *Message
-	closeOnce  sync.Once
-	ctx        context.Context
-	ctxMu      sync.Mutex
+	OnMessage func(client IClient, msg []byte)
+	OnError   func(client IClient, err error)
+	OnClose   func(client IClient, cause CloseCause)
+	OnDrop    func(client IClient, msg *Message) // 发送队列已满导致消息被丢弃时回调
+	// OnRateLimited 入站消息触发限流且处理方式为 RateLimitReply 时回调，用于回复错误帧
+	OnRateLimited func(client IClient, msg []byte)
+	conn          *websocket.Conn
+	sendBuffer    chan *Message // 高优先级发送队列
+	bulkBuffer    chan *Message // 批量推送发送队列，高优先级队列为空时才会发送
+	sendMu        sync.RWMutex  // 保护 closed 与发送队列的关闭，避免向已关闭通道写入
+	closed        bool
+	closeOnce     sync.Once
+	finishOnce    sync.Once
+	done          chan struct{} // 连接关闭后关闭，用于停止 Ping 协程
+	closing       chan struct{} // 开始关闭时关闭，唤醒等待队列空位的发送方
+	space         chan struct{} // 写协程取出消息后通知，唤醒等待队列空位的发送方
+	closeFrame    *closeFrame   // 优雅关闭时发送队列排空后需要发送的关闭帧
+	cause         CloseCause    // 关闭原因，以第一次关闭时为准
+	draining      atomic.Bool   // 为 true 时不再分发新收到的消息
+	inflight      atomic.Int64  // 正在处理中的消息数量
+	dropped       atomic.Uint64
+	limiter       *rateLimiter
+	mailbox       *mailbox // 分发器为该客户端维护的待处理队列
+	mailboxOnce   sync.Once
+	resume        *resumeSession // 开启会话恢复时绑定的会话
+	resumed       bool
+	noResume      atomic.Bool // 为 true 时断开后不保留会话，例如服务端主动断开或对端正常关闭
+	remoteIP      string
+	lastActive    atomic.Int64 // 最近一次收到业务消息的时间（UnixNano）
+	ctx           context.Context
+	ctxMu         sync.Mutex
 	*ClientConfig
 }
 
+// closeFrame WebSocket 关闭帧内容
+type closeFrame struct {
+	code int
+	text string
+}
+
 // 确保Client实现IClient接口
 var _ IClient = (*Client)(nil)
 
 type ClientConfig struct {
-	chanLength    int
-	readDeadline  time.Duration
-	writeDeadline time.Duration
-	readLimit     int64
-	supportPing   bool
-	pingPeriod    time.Duration
+	chanLength            int
+	bulkChanLength        int
+	readDeadline          time.Duration
+	writeDeadline         time.Duration
+	readLimit             int64
+	supportPing           bool
+	pingPeriod            time.Duration
+	backpressure          BackpressurePolicy
+	blockTimeout          time.Duration
+	slowConsumerCloseCode int
+	rateLimit             *RateLimitConfig
+	idleTimeout           time.Duration
+	isActivity            func(msg []byte) bool
 }
 
 type ClientOption func(*ClientConfig)
 
+// BackpressurePolicy 发送队列已满时的处理策略
+type BackpressurePolicy int
+
+const (
+	DropNewest             BackpressurePolicy = iota // 丢弃新消息（默认）
+	DropOldest                                       // 丢弃队列中最旧的消息，再放入新消息
+	BlockWithTimeout                                 // 阻塞等待队列空位，超时后丢弃新消息；Hub 群发时在后台等待
+	DisconnectSlowConsumer                           // 丢弃新消息并以指定关闭码断开慢消费者
+)
+
+// errSlowConsumer 发送队列已满且策略为断开慢消费者
+var errSlowConsumer = errors.New("send buffer is full, disconnect slow consumer")
+
+// errWouldBlock 发送队列已满且策略为阻塞等待，调用方需在释放 sendMu 后等待队列空位
+var errWouldBlock = errors.New("send buffer is full, wait for space")
+
 func WithChanLength(chanLength int) ClientOption {
 	return func(config *ClientConfig) {
 		config.chanLength = chanLength
 	}
 }
 
+// WithBulkChanLength 设置批量推送发送队列长度，默认与 WithChanLength 相同
+func WithBulkChanLength(chanLength int) ClientOption {
+	return func(config *ClientConfig) {
+		config.bulkChanLength = chanLength
+	}
+}
+
 func WithReadDeadline(timeout time.Duration) ClientOption {
 	return func(config *ClientConfig) {
 		config.readDeadline = timeout
@@ -78,6 +164,27 @@ func WithReadLimit(readLimit int64) ClientOption {
 	}
 }
 
+// WithBackpressure 设置发送队列已满时的处理策略
+func WithBackpressure(policy BackpressurePolicy) ClientOption {
+	return func(config *ClientConfig) {
+		config.backpressure = policy
+	}
+}
+
+// WithBlockTimeout 设置 BlockWithTimeout 策略下的最长等待时间
+func WithBlockTimeout(timeout time.Duration) ClientOption {
+	return func(config *ClientConfig) {
+		config.blockTimeout = timeout
+	}
+}
+
+// WithSlowConsumerCloseCode 设置 DisconnectSlowConsumer 策略下发送的关闭码
+func WithSlowConsumerCloseCode(code int) ClientOption {
+	return func(config *ClientConfig) {
+		config.slowConsumerCloseCode = code
+	}
+}
+
 func WithSupportPing(period time.Duration) ClientOption {
 	return func(config *ClientConfig) {
 		if period > 0 {
@@ -90,7 +197,8 @@ func WithSupportPing(period time.Duration) ClientOption {
 	}
 }
 
-func NewClient(conn *websocket.Conn, opts ...ClientOption) (*Client, error) {
+// newClientConfig 创建客户端配置并应用选项
+func newClientConfig(opts ...ClientOption) *ClientConfig {
 	var defaultConnectConfig = &ClientConfig{
 		chanLength:    1024,
 		readDeadline:  10 * time.Second,
@@ -98,19 +206,36 @@ func NewClient(conn *websocket.Conn, opts ...ClientOption) (*Client, error) {
 		readLimit:     0,
 		supportPing:   false, // 默认关闭 Ping
 		// 默认 Ping 周期应小于 ReadDeadline，通常为其 80%-90%
-		pingPeriod: (10 * time.Second * 8) / 10,
+		pingPeriod:            (10 * time.Second * 8) / 10,
+		backpressure:          DropNewest,
+		blockTimeout:          time.Second,
+		slowConsumerCloseCode: websocket.CloseTryAgainLater,
 	}
 
 	for _, opt := range opts {
 		opt(defaultConnectConfig)
 	}
+	if defaultConnectConfig.bulkChanLength <= 0 {
+		defaultConnectConfig.bulkChanLength = defaultConnectConfig.chanLength
+	}
+	return defaultConnectConfig
+}
 
+func NewClient(conn *websocket.Conn, opts ...ClientOption) (*Client, error) {
+	defaultConnectConfig := newClientConfig(opts...)
 	client := &Client{
 		conn:         conn,
 		sendBuffer:   make(chan *Message, defaultConnectConfig.chanLength),
+		bulkBuffer:   make(chan *Message, defaultConnectConfig.bulkChanLength),
+		done:         make(chan struct{}),
+		closing:      make(chan struct{}),
+		space:        make(chan struct{}, 1),
 		ctx:          context.Background(),
 		ClientConfig: defaultConnectConfig,
 	}
+	if client.rateLimit != nil {
+		client.limiter = newRateLimiter(client.rateLimit)
+	}
 
 	client.conn.SetPongHandler(func(appData string) error {
 		return client.conn.SetReadDeadline(time.Now().Add(client.readDeadline))
@@ -128,14 +253,22 @@ func NewClient(conn *websocket.Conn, opts ...ClientOption) (*Client, error) {
 }
 
 func (c *Client) Start() {
+	c.startIdleCheck()
 	go c.read()
 	go c.write()
+	if c.supportPing {
+		go c.ping()
+	}
 }
 
 func (c *Client) read() {
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				// 对端主动结束会话，无需保留
+				c.noResume.Store(true)
+			}
 			if websocket.IsUnexpectedCloseError(err,
 				websocket.CloseGoingAway,
 				websocket.CloseAbnormalClosure,
@@ -145,7 +278,7 @@ func (c *Client) read() {
 					c.OnError(c, wrapClientErr("read: unexpected close", err))
 				}
 			}
-			c.Close()
+			c.closeWithCause(errorCause(err))
 			return
 		}
 
@@ -154,23 +287,53 @@ func (c *Client) read() {
 			if c.OnError != nil {
 				c.OnError(c, wrapClientErr("read: set read deadline", err))
 			}
-			c.Close()
+			c.closeWithCause(errorCause(err))
 			return
 		}
 
-		if c.OnMessage != nil {
-			c.OnMessage(c, message)
+		if c.idleTimeout > 0 && (c.isActivity == nil || c.isActivity(message)) {
+			c.touch()
+		}
+		if !c.handleMessage(message) {
+			return
 		}
 	}
 }
 
+// handleMessage 分发收到的消息，连接因限流被断开时返回 false
+// 先计入 inflight 再检查 draining，保证优雅关闭观察到 inflight 为 0 后不会再有消息开始处理
+func (c *Client) handleMessage(message []byte) bool {
+	c.inflight.Add(1)
+	defer c.inflight.Add(-1)
+	if c.draining.Load() {
+		// 正在优雅关闭，丢弃新请求
+		return true
+	}
+	if c.limiter != nil && !c.limiter.allow(message) {
+		switch c.rateLimit.Action {
+		case RateLimitReply:
+			if c.OnRateLimited != nil {
+				c.OnRateLimited(c, message)
+			}
+		case RateLimitDisconnect:
+			c.abort(c.rateLimit.CloseCode, "rate limited")
+			return false
+		}
+		return true
+	}
+	if c.OnMessage != nil {
+		c.OnMessage(c, message)
+	}
+	return true
+}
+
 func (c *Client) writeMessage(messageType int, data []byte) bool {
 	err := c.conn.SetWriteDeadline(time.Now().Add(c.writeDeadline))
 	if err != nil {
 		if c.OnError != nil {
 			c.OnError(c, wrapClientErr("write: set write deadline", err))
 		}
-		c.Close()
+		c.closeWithCause(errorCause(err))
 		return false // 表示失败
 	}
 
@@ -179,63 +342,283 @@ func (c *Client) writeMessage(messageType int, data []byte) bool {
 		if c.OnError != nil {
 			c.OnError(c, wrapClientErr(fmt.Sprintf("write: write message type=%d", messageType), err))
 		}
-		c.Close()
+		c.closeWithCause(errorCause(err))
 		return false // 表示失败
 	}
 	return true // 表示成功
 }
 
+// writeFrame 写入数据帧，开启会话恢复时记录已写入的数据帧，写入失败的数据帧留待恢复后补发
+func (c *Client) writeFrame(msg *Message) bool {
+	if !c.writeMessage(msg.Type, msg.Data) {
+		if c.resume != nil && msg.seq == 0 {
+			c.resume.bufferIfDetached(c, msg)
+		}
+		return false
+	}
+	if c.resume != nil && msg.seq == 0 {
+		c.resume.record(c, msg)
+	}
+	return true
+}
+
+// bufferUnsent 写协程退出后将发送队列中剩余的数据帧交给断开的会话缓存
+// 写协程退出时发送队列已关闭，遍历会在取完剩余消息后结束
+func (c *Client) bufferUnsent() {
+	if c.resume == nil {
+		return
+	}
+	for _, queue := range []chan *Message{c.sendBuffer, c.bulkBuffer} {
+		for msg := range queue {
+			if msg.seq == 0 {
+				c.resume.bufferIfDetached(c, msg)
+			}
+		}
+	}
+}
+
+// write 写协程，高优先级队列不为空时总是先发送高优先级消息
+// 两个队列都关闭并排空后，优雅关闭时写入 Close 帧
 func (c *Client) write() {
-	if !c.supportPing {
-		for msg := range c.sendBuffer {
-			if !c.writeMessage(msg.Type, msg.Data) {
-				// 发送失败，writeMessage 内部已经处理了关闭逻辑
-				return
+	defer c.bufferUnsent()
+	high, bulk := c.sendBuffer, c.bulkBuffer
+	for high != nil || bulk != nil {
+		var msg *Message
+		var ok bool
+		select {
+		case msg, ok = <-high:
+		default:
+			select {
+			case msg, ok = <-high:
+			case msg, ok = <-bulk:
+				if !ok {
+					bulk = nil
+					continue
+				}
 			}
 		}
-		return
+		if !ok {
+			// 通道已关闭，通常是主动关闭或读协程错误触发的关闭
+			high = nil
+			continue
+		}
+		c.signalSpace()
+		if !c.writeFrame(msg) {
+			// 发送失败，writeMessage 内部已经处理了关闭逻辑
+			return
+		}
 	}
+	c.writeCloseFrame()
+}
 
-	// 支持 ping 的新逻辑
+// ping Ping 协程，使用 WriteControl 发送，不受发送队列积压影响
+func (c *Client) ping() {
 	ticker := time.NewTicker(c.pingPeriod)
 	defer ticker.Stop()
-
 	for {
 		select {
-		case msg, ok := <-c.sendBuffer:
-			if !ok {
-				// 通道已关闭，通常是主动关闭或读协程错误触发的关闭
-				// 这里不要再写 Close 帧，避免在连接已关闭时写入导致报错
+		case <-c.done:
+			return
+		case <-ticker.C:
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeDeadline))
+			if errors.Is(err, websocket.ErrCloseSent) {
+				// 已发送关闭帧，等待连接关闭
 				return
 			}
-			if !c.writeMessage(msg.Type, msg.Data) {
+			if err != nil {
+				if c.OnError != nil {
+					c.OnError(c, wrapClientErr("write: ping", err))
+				}
+				c.closeWithCause(errorCause(err))
 				return
 			}
+		}
+	}
+}
 
-		case <-ticker.C:
-			if !c.writeMessage(websocket.PingMessage, nil) {
-				return
+func (c *Client) SendText(msg []byte) error {
+	return c.enqueue(NewTextMessage(msg))
+}
+
+func (c *Client) SendBinary(msg []byte) error {
+	return c.enqueue(NewBinaryMessage(msg))
+}
+
+// Send 按消息的优先级放入对应的发送队列，未指定优先级时按 PriorityHigh 处理
+func (c *Client) Send(msg *Message) error {
+	return c.enqueue(msg)
+}
+
+// enqueue 将消息放入发送队列，客户端关闭后返回错误而不是向已关闭的通道写入
+func (c *Client) enqueue(msg *Message) error {
+	dropped, err := c.tryPush(msg)
+	if errors.Is(err, errWouldBlock) {
+		if msg.noWait {
+			// 群发不等待单个慢客户端，超时丢弃时通过 OnDrop 上报
+			go c.waitAndPush(msg)
+			return nil
+		}
+		return c.waitAndPush(msg)
+	}
+	return c.afterPush(dropped, err)
+}
+
+// tryPush 在持有 sendMu 读锁时放入发送队列，不会阻塞
+func (c *Client) tryPush(msg *Message) ([]*Message, error) {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	if c.closed {
+		if c.resume != nil && c.resume.bufferIfDetached(c, msg) {
+			// 连接已断开但会话仍在宽限期内，消息将在恢复后补发
+			return nil, nil
+		}
+		return nil, fmt.Errorf("client is closed")
+	}
+	return c.push(msg)
+}
+
+// waitAndPush 不持有 sendMu 等待队列空位，直到放入成功、客户端开始关闭或超时
+// 等待期间不持有锁，关闭不会被阻塞
+func (c *Client) waitAndPush(msg *Message) error {
+	timer := time.NewTimer(c.blockTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.space:
+		case <-c.closing:
+		case <-timer.C:
+			return c.afterPush([]*Message{msg}, fmt.Errorf("send buffer is full after waiting %s", c.blockTimeout))
+		}
+		dropped, err := c.tryPush(msg)
+		if !errors.Is(err, errWouldBlock) {
+			if err == nil {
+				// 空位通知只有一个，放入成功后转交给其他等待的发送方
+				c.signalSpace()
 			}
+			return c.afterPush(dropped, err)
 		}
 	}
 }
 
-func (c *Client) SendText(msg []byte) error {
+// signalSpace 通知等待队列空位的发送方，不会阻塞
+func (c *Client) signalSpace() {
 	select {
-	case c.sendBuffer <- &Message{Type: websocket.TextMessage, Data: msg}:
-		return nil
+	case c.space <- struct{}{}:
 	default:
-		return fmt.Errorf("send buffer is full")
 	}
 }
 
-func (c *Client) SendBinary(msg []byte) error {
+// afterPush 上报被丢弃的消息，慢消费者断开连接
+func (c *Client) afterPush(dropped []*Message, err error) error {
+	// 回调与关闭需在释放 sendMu 后执行，避免回调中关闭客户端导致死锁
+	for _, m := range dropped {
+		c.reportDrop(m)
+	}
+	if errors.Is(err, errSlowConsumer) {
+		c.abort(c.slowConsumerCloseCode, "slow consumer")
+	}
+	return err
+}
+
+// push 按背压策略将消息放入对应优先级的发送队列，返回被丢弃的消息，调用方需持有 sendMu 读锁
+func (c *Client) push(msg *Message) ([]*Message, error) {
+	queue := c.sendBuffer
+	if msg.Priority == PriorityBulk {
+		queue = c.bulkBuffer
+	}
 	select {
-	case c.sendBuffer <- &Message{Type: websocket.BinaryMessage, Data: msg}:
-		return nil
+	case queue <- msg:
+		return nil, nil
+	default:
+	}
+
+	switch c.backpressure {
+	case DropOldest:
+		var dropped []*Message
+		select {
+		case oldest := <-queue:
+			dropped = append(dropped, oldest)
+		default:
+		}
+		select {
+		case queue <- msg:
+			return dropped, nil
+		default:
+			// 并发写入抢占了空位，丢弃新消息；被取出的旧消息已无法放回
+			return append(dropped, msg), fmt.Errorf("send buffer is full")
+		}
+	case BlockWithTimeout:
+		return nil, errWouldBlock
+	case DisconnectSlowConsumer:
+		return []*Message{msg}, errSlowConsumer
+	default:
+		return []*Message{msg}, fmt.Errorf("send buffer is full")
+	}
+}
+
+// reportDrop 记录并上报被丢弃的消息
+func (c *Client) reportDrop(msg *Message) {
+	c.dropped.Add(1)
+	if c.OnDrop != nil {
+		c.OnDrop(c, msg)
+	}
+}
+
+// DroppedCount 获取因发送队列已满而被丢弃的消息数量
+func (c *Client) DroppedCount() uint64 {
+	return c.dropped.Load()
+}
+
+// PrioritySender 可按消息优先级发送的客户端，*Client 已实现
+// 自定义 IClient 可选实现，未实现时指定了优先级的消息也通过 SendText / SendBinary 发送
+type PrioritySender interface {
+	Send(msg *Message) error
+}
+
+// SendMessage 向单个客户端发送消息
+// 未指定优先级时调用 SendText / SendBinary，保留业务客户端对这两个方法的重写；
+// 指定了优先级且客户端实现 PrioritySender 时调用 Send
+func SendMessage(client IClient, msg *Message) error {
+	if msg.Priority != PriorityDefault {
+		if sender, ok := client.(PrioritySender); ok {
+			return sender.Send(msg)
+		}
+	}
+	switch msg.Type {
+	case websocket.TextMessage:
+		return client.SendText(msg.Data)
+	case websocket.BinaryMessage:
+		return client.SendBinary(msg.Data)
 	default:
-		return fmt.Errorf("send buffer is full")
+		return fmt.Errorf("unsupported message type %d", msg.Type)
+	}
+}
+
+// sendMessage Hub 群发时调用
+// 未指定优先级的消息发给 *Client 时进入批量队列，发给自定义 IClient 时仍通过 SendText / SendBinary 发送，
+// 是否阻塞由其实现决定
+func sendMessage(client IClient, msg *Message) error {
+	if msg.Type != websocket.TextMessage && msg.Type != websocket.BinaryMessage {
+		return fmt.Errorf("unsupported message type %d", msg.Type)
+	}
+	group := *msg
+	// 群发不因单个客户端的 BlockWithTimeout 策略阻塞
+	group.noWait = true
+	base, ok := client.(*Client)
+	if !ok {
+		return SendMessage(client, &group)
+	}
+	if group.Priority == PriorityDefault {
+		group.Priority = PriorityBulk
 	}
+	return base.Send(&group)
+}
+
+// isClosed 客户端是否已关闭
+func (c *Client) isClosed() bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.closed
 }
 
 // GetBaseClient 获取基础客户端实例
@@ -250,6 +633,11 @@ func (c *Client) SetContextValue(key string, value interface{}) {
 	c.ctx = context.WithValue(c.ctx, key, value)
 }
 
+// Subprotocol 获取握手时协商的子协议，未协商时返回空字符串
+func (c *Client) Subprotocol() string {
+	return c.conn.Subprotocol()
+}
+
 // GetContextValue 获取上下文值
 func (c *Client) GetContextValue(key string) interface{} {
 	c.ctxMu.Lock()
@@ -287,19 +675,94 @@ func (c *Client) GetContextBool(key string) bool {
 	return false
 }
 
+// writeCloseFrame 发送队列排空后写入关闭帧，之后等待对端回应关闭或超时后由 finishClose 关闭连接
+func (c *Client) writeCloseFrame() {
+	c.sendMu.RLock()
+	frame := c.closeFrame
+	c.sendMu.RUnlock()
+	if frame == nil {
+		return
+	}
+	data := websocket.FormatCloseMessage(frame.code, frame.text)
+	err := c.conn.WriteControl(websocket.CloseMessage, data, time.Now().Add(c.writeDeadline))
+	if err != nil {
+		if c.OnError != nil {
+			c.OnError(c, wrapClientErr("write: close frame", err))
+		}
+		c.finishClose()
+	}
+}
+
+// Close 发送正常关闭帧并立即关闭连接，未发送的消息将被丢弃
 func (c *Client) Close() {
+	c.abort(websocket.CloseNormalClosure, "")
+}
+
+// closeWithCause 连接异常时立即关闭，不发送关闭帧
+func (c *Client) closeWithCause(cause CloseCause) {
+	c.beginClose(nil, cause)
+	c.finishClose()
+}
+
+// closeGracefully 停止接收新的发送请求，等待发送队列排空后发送关闭帧；
+// 超过 timeout 仍未完成时强制关闭连接
+func (c *Client) closeGracefully(code int, text string, timeout time.Duration) {
+	c.noResume.Store(true)
+	if c.beginClose(&closeFrame{code: code, text: text}, serverCause(code, text)) {
+		time.AfterFunc(timeout, c.finishClose)
+	}
+}
+
+// abort 立即发送关闭帧并关闭连接，不等待发送队列排空
+func (c *Client) abort(code int, text string) {
+	c.noResume.Store(true)
+	if c.beginClose(nil, serverCause(code, text)) {
+		data := websocket.FormatCloseMessage(code, text)
+		err := c.conn.WriteControl(websocket.CloseMessage, data, time.Now().Add(c.writeDeadline))
+		if err != nil && c.OnError != nil {
+			c.OnError(c, wrapClientErr("abort: close frame", err))
+		}
+	}
+	c.finishClose()
+}
+
+// stopReading 停止分发新收到的消息，已在处理中的消息不受影响
+func (c *Client) stopReading() {
+	c.draining.Store(true)
+}
+
+// beginClose 标记客户端已关闭并关闭发送队列，只有第一次调用返回 true
+func (c *Client) beginClose(frame *closeFrame, cause CloseCause) bool {
+	begun := false
 	c.closeOnce.Do(func() {
+		c.sendMu.Lock()
+		c.closed = true
+		c.closeFrame = frame
+		c.cause = cause
 		close(c.sendBuffer)
+		close(c.bulkBuffer)
+		close(c.closing)
+		if c.resume != nil {
+			// 在持有 sendMu 时进入宽限期，保证关闭后的发送请求都能被会话缓存
+			c.resume.detach(c, !c.noResume.Load())
+		}
+		c.sendMu.Unlock()
+		begun = true
+	})
+	return begun
+}
 
+// finishClose 关闭底层连接并通知上层
+func (c *Client) finishClose() {
+	c.finishOnce.Do(func() {
+		close(c.done)
 		err := c.conn.Close()
-		if err != nil {
-			if c.OnError != nil {
-				c.OnError(c, wrapClientErr("close: conn close", err))
-			}
-			return
+		if err != nil && c.OnError != nil {
+			c.OnError(c, wrapClientErr("close: conn close", err))
 		}
+		// 无论底层连接关闭是否出错都需要通知上层，确保 Hub 能清理客户端
 		if c.OnClose != nil {
-			c.OnClose(c)
+			c.OnClose(c, c.CloseCause())
 		}
 	})
 }
@@ -0,0 +1,292 @@
+package wshub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient 建立一对真实的 WebSocket 连接，返回未启动的服务端 Client 和对端连接
+func newTestClient(t *testing.T, opts ...ClientOption) (*Client, *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	client, err := NewClient(<-conns, opts...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client, peer
+}
+
+// dropRecorder 记录 OnDrop 回调中被丢弃的消息
+type dropRecorder struct {
+	mu      sync.Mutex
+	dropped []string
+}
+
+func (r *dropRecorder) onDrop(_ IClient, msg *Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropped = append(r.dropped, string(msg.Data))
+}
+
+func (r *dropRecorder) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.dropped...)
+}
+
+func TestBackpressurePolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      BackpressurePolicy
+		wantErr     bool
+		wantDropped []string
+		wantQueued  string
+		wantClosed  bool
+	}{
+		{name: "drop newest", policy: DropNewest, wantErr: true, wantDropped: []string{"second"}, wantQueued: "first"},
+		{name: "drop oldest", policy: DropOldest, wantDropped: []string{"first"}, wantQueued: "second"},
+		{name: "block with timeout", policy: BlockWithTimeout, wantErr: true, wantDropped: []string{"second"}, wantQueued: "first"},
+		{name: "disconnect slow consumer", policy: DisconnectSlowConsumer, wantErr: true, wantDropped: []string{"second"}, wantClosed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t,
+				WithChanLength(1),
+				WithBackpressure(tt.policy),
+				WithBlockTimeout(20*time.Millisecond),
+			)
+			recorder := &dropRecorder{}
+			client.OnDrop = recorder.onDrop
+
+			if err := client.SendText([]byte("first")); err != nil {
+				t.Fatalf("first send: %v", err)
+			}
+			err := client.SendText([]byte("second"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("second send err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := recorder.snapshot(); strings.Join(got, ",") != strings.Join(tt.wantDropped, ",") {
+				t.Errorf("dropped = %v, want %v", got, tt.wantDropped)
+			}
+			if got := client.DroppedCount(); got != uint64(len(tt.wantDropped)) {
+				t.Errorf("DroppedCount() = %d, want %d", got, len(tt.wantDropped))
+			}
+			if got := client.isClosed(); got != tt.wantClosed {
+				t.Errorf("closed = %v, want %v", got, tt.wantClosed)
+			}
+			if tt.wantQueued != "" {
+				if msg := <-client.sendBuffer; string(msg.Data) != tt.wantQueued {
+					t.Errorf("queued = %q, want %q", msg.Data, tt.wantQueued)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockWithTimeoutDoesNotBlockClose(t *testing.T) {
+	client, _ := newTestClient(t,
+		WithChanLength(1),
+		WithBackpressure(BlockWithTimeout),
+		WithBlockTimeout(time.Minute),
+	)
+	if err := client.SendText([]byte("first")); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- client.SendText([]byte("second")) }()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked by a sender waiting for queue space")
+	}
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Error("send after close succeeded, want error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting sender was not woken by Close")
+	}
+}
+
+func TestBlockWithTimeoutWakesWhenWriterDrains(t *testing.T) {
+	client, peer := newTestClient(t,
+		WithChanLength(1),
+		WithBackpressure(BlockWithTimeout),
+		WithBlockTimeout(time.Minute),
+	)
+	if err := client.SendText([]byte("first")); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	result := make(chan error, 1)
+	go func() { result <- client.SendText([]byte("second")) }()
+	time.Sleep(20 * time.Millisecond)
+
+	client.Start()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("blocked send: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked sender was not woken after the writer drained the queue")
+	}
+
+	for _, want := range []string{"first", "second"} {
+		_ = peer.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := peer.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("received %q, want %q", data, want)
+		}
+	}
+}
+
+func TestGroupSendDoesNotWaitForSlowClient(t *testing.T) {
+	client, _ := newTestClient(t,
+		WithBulkChanLength(1),
+		WithBackpressure(BlockWithTimeout),
+		WithBlockTimeout(50*time.Millisecond),
+	)
+	dropped := make(chan *Message, 1)
+	client.OnDrop = func(_ IClient, msg *Message) { dropped <- msg }
+
+	if err := sendMessage(client, NewTextMessage([]byte("first"))); err != nil {
+		t.Fatalf("first group send: %v", err)
+	}
+	start := time.Now()
+	if err := sendMessage(client, NewTextMessage([]byte("second"))); err != nil {
+		t.Fatalf("second group send: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("group send waited %s for a full queue", elapsed)
+	}
+
+	select {
+	case msg := <-dropped:
+		if string(msg.Data) != "second" || msg.Priority != PriorityBulk {
+			t.Errorf("dropped %q with priority %d, want bulk %q", msg.Data, msg.Priority, "second")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("background wait did not report the drop after the block timeout")
+	}
+}
+
+func TestSendAfterCloseReturnsError(t *testing.T) {
+	client, _ := newTestClient(t)
+	client.Close()
+	if err := client.SendText([]byte("late")); err == nil || errors.Is(err, errWouldBlock) {
+		t.Errorf("send after close err = %v, want client is closed", err)
+	}
+}
+
+// recordingClient 记录调用了哪个发送方法的自定义 IClient，未实现的方法不会被调用
+type recordingClient struct {
+	IClient
+	calls []string
+}
+
+func (c *recordingClient) SendText(msg []byte) error {
+	c.calls = append(c.calls, "text:"+string(msg))
+	return nil
+}
+
+func (c *recordingClient) SendBinary(msg []byte) error {
+	c.calls = append(c.calls, "binary:"+string(msg))
+	return nil
+}
+
+func (c *recordingClient) recorded() []string { return c.calls }
+
+// prioritySendingClient 额外实现 PrioritySender 的自定义 IClient
+type prioritySendingClient struct {
+	recordingClient
+}
+
+func (c *prioritySendingClient) Send(msg *Message) error {
+	c.calls = append(c.calls, fmt.Sprintf("send:%d:%s", msg.Priority, msg.Data))
+	return nil
+}
+
+func TestSendMessageDispatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		client interface {
+			IClient
+			recorded() []string
+		}
+		msg       *Message
+		group     bool
+		wantCalls []string
+	}{
+		{name: "default text", client: &recordingClient{}, msg: NewTextMessage([]byte("a")), wantCalls: []string{"text:a"}},
+		{name: "default binary", client: &recordingClient{}, msg: NewBinaryMessage([]byte("b")), wantCalls: []string{"binary:b"}},
+		{name: "group keeps overrides", client: &recordingClient{}, msg: NewTextMessage([]byte("c")), group: true, wantCalls: []string{"text:c"}},
+		{name: "priority without sender", client: &recordingClient{}, msg: &Message{Type: websocket.BinaryMessage, Data: []byte("d"), Priority: PriorityBulk}, wantCalls: []string{"binary:d"}},
+		{name: "default with sender", client: &prioritySendingClient{}, msg: NewTextMessage([]byte("e")), group: true, wantCalls: []string{"text:e"}},
+		{name: "priority with sender", client: &prioritySendingClient{}, msg: &Message{Type: websocket.TextMessage, Data: []byte("f"), Priority: PriorityBulk}, group: true, wantCalls: []string{fmt.Sprintf("send:%d:f", PriorityBulk)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			send := SendMessage
+			if tt.group {
+				send = sendMessage
+			}
+			if err := send(tt.client, tt.msg); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if got := tt.client.recorded(); strings.Join(got, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGroupSendUsesBulkLaneForBaseClient(t *testing.T) {
+	client, _ := newTestClient(t)
+	if err := sendMessage(client, NewTextMessage([]byte("push"))); err != nil {
+		t.Fatalf("group send: %v", err)
+	}
+	if err := SendMessage(client, NewTextMessage([]byte("response"))); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(client.bulkBuffer) != 1 || len(client.sendBuffer) != 1 {
+		t.Errorf("bulk = %d, high = %d, want 1 and 1", len(client.bulkBuffer), len(client.sendBuffer))
+	}
+}
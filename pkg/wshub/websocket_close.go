@@ -0,0 +1,83 @@
+package wshub
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/gorilla/websocket"
+)
+
+// CloseSource 连接关闭的发起方
+type CloseSource int
+
+const (
+	CloseSourceServer  CloseSource = iota // 服务端主动关闭，例如踢下线、限流、空闲超时、服务关闭
+	CloseSourcePeer                       // 客户端发送了关闭帧
+	CloseSourceTimeout                    // 读超时，客户端未按时回应心跳
+	CloseSourceNetwork                    // 网络或读写错误导致连接中断
+)
+
+func (s CloseSource) String() string {
+	switch s {
+	case CloseSourceServer:
+		return "server"
+	case CloseSourcePeer:
+		return "peer"
+	case CloseSourceTimeout:
+		return "timeout"
+	case CloseSourceNetwork:
+		return "network"
+	default:
+		return fmt.Sprintf("CloseSource(%d)", int(s))
+	}
+}
+
+// CloseCause 连接关闭原因，通过 OnClose 回调传给上层
+type CloseCause struct {
+	Source CloseSource
+	Code   int    // 关闭码：服务端发送或从客户端收到的关闭码，连接异常中断时为 CloseAbnormalClosure
+	Text   string // 关闭原因
+	Err    error  // 导致关闭的底层错误，主动关闭时为 nil
+}
+
+func (c CloseCause) String() string {
+	if c.Err != nil {
+		return fmt.Sprintf("%s close %d: %v", c.Source, c.Code, c.Err)
+	}
+	return fmt.Sprintf("%s close %d: %s", c.Source, c.Code, c.Text)
+}
+
+// serverCause 服务端主动关闭的原因
+func serverCause(code int, text string) CloseCause {
+	return CloseCause{Source: CloseSourceServer, Code: code, Text: text}
+}
+
+// errorCause 根据读写错误推断关闭原因
+func errorCause(err error) CloseCause {
+	var closeErr *websocket.CloseError
+	// 连接意外断开时 gorilla 同样返回 CloseAbnormalClosure 的 CloseError，此时对端并未发送关闭帧
+	if errors.As(err, &closeErr) && closeErr.Code != websocket.CloseAbnormalClosure {
+		return CloseCause{Source: CloseSourcePeer, Code: closeErr.Code, Text: closeErr.Text}
+	}
+	cause := CloseCause{Source: CloseSourceNetwork, Code: websocket.CloseAbnormalClosure, Err: err}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		cause.Source = CloseSourceTimeout
+	}
+	return cause
+}
+
+// CloseWithReason 以指定关闭码和原因关闭连接
+// 已放入发送队列的消息会先发送完，再发送关闭帧；对端在写超时内未回应时强制关闭。
+// 业务自定义关闭码建议使用 4000-4999，客户端可据此决定是否重连，例如被踢下线时不再重连
+func (c *Client) CloseWithReason(code int, text string) {
+	c.closeGracefully(code, text, c.writeDeadline)
+}
+
+// CloseCause 获取连接关闭原因，连接未关闭时返回零值
+func (c *Client) CloseCause() CloseCause {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.cause
+}
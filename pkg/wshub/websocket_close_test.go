@@ -0,0 +1,114 @@
+package wshub_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wshubtest"
+)
+
+func TestCloseCause(t *testing.T) {
+	tests := []struct {
+		name       string
+		close      func(client wshub.IClient, conn *websocket.Conn)
+		wantFrames []string // 关闭前客户端收到的数据帧
+		wantPeer   int      // 客户端收到的关闭码，0 表示未收到关闭帧
+		wantText   string
+		wantCause  wshub.CloseCause
+	}{
+		{
+			name: "close with reason",
+			close: func(client wshub.IClient, _ *websocket.Conn) {
+				// 已放入发送队列的消息先于关闭帧送达
+				_ = client.SendText([]byte("a"))
+				_ = client.SendText([]byte("b"))
+				client.CloseWithReason(4001, "kicked")
+			},
+			wantFrames: []string{"a", "b"},
+			wantPeer:   4001,
+			wantText:   "kicked",
+			wantCause:  wshub.CloseCause{Source: wshub.CloseSourceServer, Code: 4001, Text: "kicked"},
+		},
+		{
+			name:      "close",
+			close:     func(client wshub.IClient, _ *websocket.Conn) { client.Close() },
+			wantPeer:  websocket.CloseNormalClosure,
+			wantCause: wshub.CloseCause{Source: wshub.CloseSourceServer, Code: websocket.CloseNormalClosure},
+		},
+		{
+			name: "peer close",
+			close: func(_ wshub.IClient, conn *websocket.Conn) {
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4002, "bye"))
+			},
+			// 服务端只回应对端的关闭码
+			wantPeer:  4002,
+			wantCause: wshub.CloseCause{Source: wshub.CloseSourcePeer, Code: 4002, Text: "bye"},
+		},
+		{
+			name:      "network",
+			close:     func(_ wshub.IClient, conn *websocket.Conn) { _ = conn.NetConn().Close() },
+			wantCause: wshub.CloseCause{Source: wshub.CloseSourceNetwork, Code: websocket.CloseAbnormalClosure},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub()
+			if err != nil {
+				t.Fatalf("new hub: %v", err)
+			}
+			server := wshubtest.NewServer(hub)
+			defer server.Close()
+
+			conn, _, err := server.Dial("", nil)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			client, err := server.WaitOpen(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.close(client, conn)
+			if tt.wantPeer != 0 {
+				var frames []string
+				_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+				for {
+					_, data, err := conn.ReadMessage()
+					if err == nil {
+						frames = append(frames, string(data))
+						continue
+					}
+					var closeErr *websocket.CloseError
+					if !errors.As(err, &closeErr) || closeErr.Code != tt.wantPeer || closeErr.Text != tt.wantText {
+						t.Errorf("read error = %v, want close %d %q", err, tt.wantPeer, tt.wantText)
+					}
+					break
+				}
+				if strings.Join(frames, ",") != strings.Join(tt.wantFrames, ",") {
+					t.Errorf("frames = %v, want %v", frames, tt.wantFrames)
+				}
+			}
+
+			event, err := server.WaitClose(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cause := event.Cause
+			if cause.Source != tt.wantCause.Source || cause.Code != tt.wantCause.Code || cause.Text != tt.wantCause.Text {
+				t.Errorf("cause = %v, want %v", cause, tt.wantCause)
+			}
+			if (cause.Err != nil) != (tt.wantCause.Source == wshub.CloseSourceNetwork) {
+				t.Errorf("cause error = %v", cause.Err)
+			}
+			if got := event.Client.GetBaseClient().CloseCause(); got.Code != tt.wantCause.Code {
+				t.Errorf("CloseCause().Code = %d, want %d", got.Code, tt.wantCause.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package wshub
+
+import (
+	"errors"
+	"sync"
+)
+
+// Dispatcher 消息分发器
+// 使用固定数量的工作协程处理客户端消息，工作协程数量即为整个 Hub 同时处理中的消息上限。
+// 开启顺序保证时，同一客户端的消息按到达顺序依次处理；被标记为可并行的消息不受顺序约束。
+type Dispatcher struct {
+	workers    int
+	queue      chan func()
+	ordered    bool
+	maxPending int
+	isParallel func(msg []byte) bool
+	wg         sync.WaitGroup
+	mu         sync.RWMutex // 保护 stopped 与 queue 的关闭
+	stopped    bool
+}
+
+type DispatcherOption func(*Dispatcher)
+
+// WithOrdered 设置是否保证同一客户端的消息按顺序处理，默认开启
+func WithOrdered(ordered bool) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.ordered = ordered
+	}
+}
+
+// WithParallelClassifier 设置可并行处理的消息判定函数，返回 true 的消息不参与客户端内排序
+func WithParallelClassifier(isParallel func(msg []byte) bool) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.isParallel = isParallel
+	}
+}
+
+// WithQueueLength 设置全局待处理队列长度，队列已满时读协程会阻塞等待，<= 0 时使用默认值
+func WithQueueLength(length int) DispatcherOption {
+	return func(d *Dispatcher) {
+		if length > 0 {
+			d.queue = make(chan func(), length)
+		}
+	}
+}
+
+// WithMaxPendingPerClient 设置单个客户端最多排队的消息数，超过后该客户端的读协程会阻塞等待，<= 0 时使用默认值
+func WithMaxPendingPerClient(maxPending int) DispatcherOption {
+	return func(d *Dispatcher) {
+		if maxPending > 0 {
+			d.maxPending = maxPending
+		}
+	}
+}
+
+// NewDispatcher 创建分发器并启动 workers 个工作协程
+func NewDispatcher(workers int, opts ...DispatcherOption) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &Dispatcher{
+		workers:    workers,
+		queue:      make(chan func(), workers*4),
+		ordered:    true,
+		maxPending: 64,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.wg.Add(d.workers)
+	for i := 0; i < d.workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// mailbox 单个客户端的待处理消息队列，保证顺序处理
+type mailbox struct {
+	mu      sync.Mutex
+	pending []func()
+	running bool
+	slots   chan struct{} // 限制单个客户端排队中的消息数量
+}
+
+// Dispatch 分发客户端消息，handler 在工作协程中执行
+func (d *Dispatcher) Dispatch(client *Client, msg []byte, handler func()) error {
+	client.mailboxOnce.Do(func() {
+		client.mailbox = &mailbox{slots: make(chan struct{}, d.maxPending)}
+	})
+	mb := client.mailbox
+
+	mb.slots <- struct{}{}
+	client.inflight.Add(1)
+	task := func() {
+		defer func() {
+			client.inflight.Add(-1)
+			<-mb.slots
+		}()
+		handler()
+	}
+
+	if !d.ordered || (d.isParallel != nil && d.isParallel(msg)) {
+		if err := d.submit(task); err != nil {
+			client.inflight.Add(-1)
+			<-mb.slots
+			return err
+		}
+		return nil
+	}
+
+	mb.mu.Lock()
+	mb.pending = append(mb.pending, task)
+	if mb.running {
+		mb.mu.Unlock()
+		return nil
+	}
+	mb.running = true
+	mb.mu.Unlock()
+
+	if err := d.submit(mb.run); err != nil {
+		// 分发器已停止，直接丢弃排队中的消息
+		mb.mu.Lock()
+		for range mb.pending {
+			client.inflight.Add(-1)
+			<-mb.slots
+		}
+		mb.pending = nil
+		mb.running = false
+		mb.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// run 依次处理客户端排队中的消息，直到队列为空
+func (mb *mailbox) run() {
+	for {
+		mb.mu.Lock()
+		if len(mb.pending) == 0 {
+			mb.running = false
+			mb.mu.Unlock()
+			return
+		}
+		task := mb.pending[0]
+		mb.pending[0] = nil
+		mb.pending = mb.pending[1:]
+		mb.mu.Unlock()
+
+		task()
+	}
+}
+
+// submit 将任务放入全局队列
+func (d *Dispatcher) submit(task func()) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return errors.New("dispatcher is stopped")
+	}
+	d.queue <- task
+	return nil
+}
+
+// work 工作协程
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for task := range d.queue {
+		task()
+	}
+}
+
+// Stop 停止接收新消息，等待已排队的消息处理完成
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.stopped = true
+	close(d.queue)
+	d.mu.Unlock()
+	d.wg.Wait()
+}
@@ -0,0 +1,148 @@
+package wshub
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcherPerClientOrdering(t *testing.T) {
+	const clients, messages = 4, 200
+	d := NewDispatcher(8)
+
+	var mu sync.Mutex
+	got := make([][]int, clients)
+	var wg sync.WaitGroup
+	for c := 0; c < clients; c++ {
+		client := &Client{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				err := d.Dispatch(client, nil, func() {
+					if i%17 == 0 {
+						time.Sleep(time.Millisecond)
+					}
+					mu.Lock()
+					got[c] = append(got[c], i)
+					mu.Unlock()
+				})
+				if err != nil {
+					t.Errorf("dispatch: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	d.Stop()
+
+	for c, order := range got {
+		if len(order) != messages {
+			t.Fatalf("client %d handled %d messages, want %d", c, len(order), messages)
+		}
+		for i, v := range order {
+			if v != i {
+				t.Fatalf("client %d handled message %d at position %d", c, v, i)
+			}
+		}
+	}
+}
+
+func TestDispatcherBypassesOrdering(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []DispatcherOption
+	}{
+		{name: "parallel classified", opts: []DispatcherOption{
+			WithParallelClassifier(func(msg []byte) bool { return string(msg) == "parallel" }),
+		}},
+		{name: "unordered", opts: []DispatcherOption{WithOrdered(false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDispatcher(2, tt.opts...)
+			defer d.Stop()
+			client := &Client{}
+
+			release := make(chan struct{})
+			started := make(chan struct{})
+			if err := d.Dispatch(client, []byte("slow"), func() {
+				close(started)
+				<-release
+			}); err != nil {
+				t.Fatalf("dispatch slow: %v", err)
+			}
+			<-started
+
+			handled := make(chan struct{})
+			if err := d.Dispatch(client, []byte("parallel"), func() { close(handled) }); err != nil {
+				t.Fatalf("dispatch parallel: %v", err)
+			}
+			select {
+			case <-handled:
+			case <-time.After(time.Second):
+				t.Error("message waited behind a slow message of the same client")
+			}
+			close(release)
+		})
+	}
+}
+
+func TestDispatcherOrderedWaitsForPrevious(t *testing.T) {
+	d := NewDispatcher(2)
+	defer d.Stop()
+	client := &Client{}
+
+	release := make(chan struct{})
+	if err := d.Dispatch(client, nil, func() { <-release }); err != nil {
+		t.Fatalf("dispatch first: %v", err)
+	}
+	handled := make(chan struct{})
+	if err := d.Dispatch(client, nil, func() { close(handled) }); err != nil {
+		t.Fatalf("dispatch second: %v", err)
+	}
+	select {
+	case <-handled:
+		t.Fatal("second message handled before the first finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if got := client.inflight.Load(); got != 2 {
+		t.Errorf("inflight = %d, want 2", got)
+	}
+
+	close(release)
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("second message not handled after the first finished")
+	}
+}
+
+func TestDispatcherStop(t *testing.T) {
+	d := NewDispatcher(1)
+	client := &Client{}
+
+	var handled atomic.Int32
+	for i := 0; i < 3; i++ {
+		if err := d.Dispatch(client, nil, func() {
+			time.Sleep(5 * time.Millisecond)
+			handled.Add(1)
+		}); err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+	}
+	// Stop 等待已排队的消息处理完成
+	d.Stop()
+	if got := handled.Load(); got != 3 {
+		t.Fatalf("Stop returned after %d of 3 queued messages were handled", got)
+	}
+
+	if err := d.Dispatch(client, nil, func() { t.Error("handled after Stop") }); err == nil {
+		t.Error("Dispatch after Stop succeeded, want error")
+	}
+	if got := client.inflight.Load(); got != 0 {
+		t.Errorf("inflight = %d after Stop, want 0", got)
+	}
+}
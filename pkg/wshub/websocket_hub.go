@@ -1,9 +1,15 @@
 package wshub
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -15,18 +21,52 @@ var (
 
 type ServerConfig struct {
 	UpGrader websocket.Upgrader
+	CertFile string // TLS 证书文件，与 KeyFile 同时设置时启用 wss
+	KeyFile  string // TLS 私钥文件
+	// ResumeGrace 会话恢复宽限期，> 0 时开启会话恢复
+	ResumeGrace time.Duration
+	// ResumeBufferSize 每个会话保留的最近数据帧数量
+	ResumeBufferSize int
+	// MaxConnections 最大连接数，<= 0 表示不限制
+	MaxConnections int
+	// MaxConnectionsPerIP 单个客户端 IP 的最大连接数，<= 0 表示不限制
+	MaxConnectionsPerIP int
+	// TrustedProxies 可信代理的 IP 或 CIDR
+	TrustedProxies []string
+	// Start 创建的 http.Server 的超时设置，<= 0 表示不限制
+	ReadHeaderTimeout time.Duration
+	HTTPReadTimeout   time.Duration
+	HTTPWriteTimeout  time.Duration
+	HTTPIdleTimeout   time.Duration
 }
 
 type ServerOption func(*ServerConfig)
 
 type WebSocketHub struct {
-	OnOpen        func(client IClient)
-	OnClose       func(client IClient)
-	OnMessage     func(client IClient, msg []byte)
-	OnError       func(client IClient, err error)
-	clientFactory func(baseClient *Client) IClient
+	OnOpen          func(client IClient)
+	OnClose         func(client IClient, cause CloseCause)
+	OnMessage       func(client IClient, msg []byte)
+	OnReceive       func(client IClient, msg []byte) // 收到消息后、放入分发器之前在读协程中按接收顺序回调，应只做轻量的登记
+	OnError         func(client IClient, err error)
+	OnDispatchError func(client IClient, msg []byte, err error) // 消息未能放入分发器（分发器已停止）、不会交给 OnMessage 时回调，用于清理 OnReceive 中登记的状态
+	OnDrop          func(client IClient, msg *Message)          // 客户端发送队列已满导致消息被丢弃时回调
+	OnRateLimited   func(client IClient, msg []byte)            // 入站消息触发限流且需要回复错误帧时回调
+	clientFactory   func(baseClient *Client) IClient
 	*ServerConfig
-	clientOptions []ClientOption
+	clientOptions  []ClientOption
+	dispatcher     *Dispatcher
+	authenticator  Authenticator
+	resumes        *resumeManager
+	admission      admission
+	trustedProxies []*net.IPNet        // 由 ServerConfig.TrustedProxies 解析得到
+	clients        map[*Client]IClient // 在线客户端，key 为底层客户端
+	clientsMu      sync.RWMutex
+	rooms          map[string]map[*Client]IClient  // 主题 -> 成员
+	clientRooms    map[*Client]map[string]struct{} // 客户端 -> 已加入的主题
+	roomsMu        sync.RWMutex
+	server         *http.Server
+	serverMu       sync.Mutex
+	shuttingDown   atomic.Bool
 }
 
 func getDefaultServerConfig() *ServerConfig {
@@ -38,18 +78,52 @@ func getDefaultServerConfig() *ServerConfig {
 				return true // 默认允许所有跨域请求
 			},
 		},
+		ReadHeaderTimeout: 10 * time.Second,
 	}
 }
 
+// NewHub 创建独立的 Hub 实例，可在同一进程中运行多个 Hub
+func NewHub(opts ...ServerOption) (*WebSocketHub, error) {
+	wsh := &WebSocketHub{
+		ServerConfig: getDefaultServerConfig(),
+		resumes:      newResumeManager(),
+		admission:    admission{perIP: make(map[string]int)},
+		clients:      make(map[*Client]IClient),
+		rooms:        make(map[string]map[*Client]IClient),
+		clientRooms:  make(map[*Client]map[string]struct{}),
+	}
+	if err := wsh.applyOptions(opts...); err != nil {
+		return nil, err
+	}
+	return wsh, nil
+}
+
+// GetInstance 获取进程内共享的 Hub 实例
 func GetInstance() *WebSocketHub {
 	once.Do(func() {
-		instance = &WebSocketHub{
-			ServerConfig: getDefaultServerConfig(),
-		}
+		// 不带选项时不会返回错误
+		instance, _ = NewHub()
 	})
 	return instance
 }
 
+// applyOptions 应用服务端选项并校验
+func (wsh *WebSocketHub) applyOptions(opts ...ServerOption) error {
+	for _, opt := range opts {
+		opt(wsh.ServerConfig)
+	}
+	if (wsh.CertFile == "") != (wsh.KeyFile == "") {
+		// 只设置其中一个通常是配置遗漏，不能静默退回到 ws
+		return wrapHubErr("tls", errors.New("certFile and keyFile must be set together"))
+	}
+	trustedProxies, err := parseTrustedProxies(wsh.TrustedProxies)
+	if err != nil {
+		return wrapHubErr("trusted proxies", err)
+	}
+	wsh.trustedProxies = trustedProxies
+	return nil
+}
+
 // WithReadBufferSize 设置读缓冲区大小
 func WithReadBufferSize(size int) ServerOption {
 	return func(cfg *ServerConfig) {
@@ -71,6 +145,33 @@ func WithCheckOrigin(checkOrigin func(*http.Request) bool) ServerOption {
 	}
 }
 
+// WithSubprotocols 设置服务端支持的子协议，按优先级排列
+// 握手时从客户端 Sec-WebSocket-Protocol 请求头中选择第一个支持的子协议，可通过 Client.Subprotocol 获取；
+// 客户端未请求或没有支持的子协议时照常升级，子协议为空
+func WithSubprotocols(protocols ...string) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.UpGrader.Subprotocols = protocols
+	}
+}
+
+// WithTLS 设置 TLS 证书和私钥文件，启用后 Start 以 wss 方式监听，证书文件变更后自动重新加载
+func WithTLS(certFile, keyFile string) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.CertFile = certFile
+		cfg.KeyFile = keyFile
+	}
+}
+
+// WithHTTPTimeouts 设置 Start 创建的 http.Server 的超时，只影响握手阶段，升级后的连接由客户端读写超时控制
+func WithHTTPTimeouts(readHeader, read, write, idle time.Duration) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.ReadHeaderTimeout = readHeader
+		cfg.HTTPReadTimeout = read
+		cfg.HTTPWriteTimeout = write
+		cfg.HTTPIdleTimeout = idle
+	}
+}
+
 // SetClientFactory 设置客户端工厂函数
 func (wsh *WebSocketHub) SetClientFactory(factory func(baseClient *Client) IClient) {
 	wsh.clientFactory = factory
@@ -81,9 +182,58 @@ func (wsh *WebSocketHub) SetClientOptions(opts ...ClientOption) {
 	wsh.clientOptions = opts
 }
 
+// SetDispatcher 设置消息分发器，设置后 OnMessage 在分发器的工作协程中异步执行
+func (wsh *WebSocketHub) SetDispatcher(dispatcher *Dispatcher) {
+	wsh.dispatcher = dispatcher
+}
+
+// ServeHTTP 实现 http.Handler，处理 WebSocket 升级请求，可挂载到自定义的路由或 httptest.Server
+func (wsh *WebSocketHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wsh.processRequest(w, r)
+}
+
+// Handler 获取处理 WebSocket 升级请求的 http.Handler，用于与其他 HTTP 路由共用同一个服务
+// 此时 TLS 与 HTTP 超时由调用方的 http.Server 负责，Shutdown 只关闭已建立的连接
+func (wsh *WebSocketHub) Handler() http.Handler {
+	return wsh
+}
+
 func (wsh *WebSocketHub) processRequest(w http.ResponseWriter, r *http.Request) {
-	conn, err := wsh.UpGrader.Upgrade(w, r, nil)
+	if wsh.shuttingDown.Load() {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	ip := wsh.clientIP(r)
+	if !wsh.admit(w, ip) {
+		return
+	}
+	// 连接建立失败时释放名额，建立成功后在 OnClose 中释放
+	admitted := false
+	defer func() {
+		if !admitted {
+			wsh.admission.release(ip)
+		}
+	}()
+
+	values, ok := wsh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	header := http.Header{}
+	handshake, err := wsh.prepareResume(r, header)
+	if err != nil {
+		http.Error(w, "failed to prepare session", http.StatusInternalServerError)
+		if wsh.OnError != nil {
+			wsh.OnError(nil, wrapHubErr("prepare resume", err))
+		}
+		return
+	}
+
+	conn, err := wsh.UpGrader.Upgrade(w, r, header)
 	if err != nil {
+		handshake.abandon()
 		if wsh.OnError != nil {
 			wsh.OnError(nil, wrapHubErr("upgrade", err))
 		}
@@ -92,12 +242,15 @@ func (wsh *WebSocketHub) processRequest(w http.ResponseWriter, r *http.Request)
 
 	baseClient, err := NewClient(conn, wsh.clientOptions...)
 	if err != nil {
+		handshake.abandon()
 		if wsh.OnError != nil {
 			wsh.OnError(nil, err)
 		}
 		return
 	}
 
+	baseClient.remoteIP = ip
+
 	var client IClient
 	if wsh.clientFactory != nil {
 		client = wsh.clientFactory(baseClient)
@@ -105,9 +258,38 @@ func (wsh *WebSocketHub) processRequest(w http.ResponseWriter, r *http.Request)
 		client = baseClient
 	}
 
+	var topics []string
+	if handshake != nil {
+		// 恢复的会话继承旧连接的 Context，并在启动前放入需要补发的数据帧
+		topics = wsh.restoreSession(handshake, baseClient, client)
+	}
+	// 鉴权得到的身份信息写入客户端 Context，OnOpen 中即可使用
+	for key, value := range values {
+		baseClient.SetContextValue(key, value)
+	}
+
 	baseClient.OnMessage = func(_ IClient, msg []byte) {
-		if wsh.OnMessage != nil {
+		if wsh.OnMessage == nil {
+			return
+		}
+		if wsh.OnReceive != nil {
+			wsh.OnReceive(client, msg)
+		}
+		if wsh.dispatcher == nil {
 			wsh.OnMessage(client, msg)
+			return
+		}
+		err := wsh.dispatcher.Dispatch(baseClient, msg, func() {
+			wsh.OnMessage(client, msg)
+		})
+		if err == nil {
+			return
+		}
+		if wsh.OnDispatchError != nil {
+			wsh.OnDispatchError(client, msg, err)
+		}
+		if wsh.OnError != nil {
+			wsh.OnError(client, wrapHubErr("dispatch", err))
 		}
 	}
 	baseClient.OnError = func(_ IClient, err error) {
@@ -115,12 +297,41 @@ func (wsh *WebSocketHub) processRequest(w http.ResponseWriter, r *http.Request)
 			wsh.OnError(client, err)
 		}
 	}
-	baseClient.OnClose = func(_ IClient) {
-		if wsh.OnClose != nil {
-			wsh.OnClose(client)
+	baseClient.OnDrop = func(_ IClient, msg *Message) {
+		if wsh.OnDrop != nil {
+			wsh.OnDrop(client, msg)
+		}
+	}
+	baseClient.OnRateLimited = func(_ IClient, msg []byte) {
+		if wsh.OnRateLimited != nil {
+			wsh.OnRateLimited(client, msg)
+		}
+	}
+	// 只有调用过 OnOpen 的连接关闭时才调用 OnClose
+	var opened atomic.Bool
+	baseClient.OnClose = func(_ IClient, cause CloseCause) {
+		wsh.removeClient(baseClient)
+		wsh.admission.release(ip)
+		if baseClient.resume != nil {
+			baseClient.resume.saveTopics(baseClient, wsh.Topics(client))
+		}
+		wsh.leaveAllRooms(baseClient)
+		if wsh.OnClose != nil && opened.Load() {
+			wsh.OnClose(client, cause)
 		}
 	}
 
+	admitted = true
+	wsh.addClient(baseClient, client)
+	// Shutdown 先设置标记再获取客户端快照，握手期间开始关闭时，快照中可能没有这个连接，需要在登记后再次检查
+	if wsh.shuttingDown.Load() {
+		baseClient.abort(websocket.CloseGoingAway, "server shutdown")
+		return
+	}
+	for _, topic := range topics {
+		wsh.Join(client, topic)
+	}
+	opened.Store(true)
 	if wsh.OnOpen != nil {
 		wsh.OnOpen(client)
 	}
@@ -128,16 +339,127 @@ func (wsh *WebSocketHub) processRequest(w http.ResponseWriter, r *http.Request)
 	baseClient.Start()
 }
 
+// Start 创建独立的 http.Server 并在 route 上监听，阻塞直到服务关闭
+// opts 会在启动前应用，建议在 NewHub 时传入
 func (wsh *WebSocketHub) Start(route string, port int, opts ...ServerOption) error {
-	for _, opt := range opts {
-		opt(wsh.ServerConfig)
+	if err := wsh.applyOptions(opts...); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(route, wsh.Handler())
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: wsh.ReadHeaderTimeout,
+		ReadTimeout:       wsh.HTTPReadTimeout,
+		WriteTimeout:      wsh.HTTPWriteTimeout,
+		IdleTimeout:       wsh.HTTPIdleTimeout,
+	}
+	useTLS := wsh.CertFile != "" && wsh.KeyFile != ""
+	if useTLS {
+		reloader, err := newCertReloader(wsh.CertFile, wsh.KeyFile, func(err error) {
+			if wsh.OnError != nil {
+				wsh.OnError(nil, wrapHubErr("reload certificate", err))
+			}
+		})
+		if err != nil {
+			return wrapHubErr("tls", err)
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
 	}
+	wsh.serverMu.Lock()
+	wsh.server = server
+	wsh.serverMu.Unlock()
 
-	http.HandleFunc(route, wsh.processRequest)
-	err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
+	var err error
+	if useTLS {
+		// 证书由 TLSConfig.GetCertificate 提供
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		// Shutdown 触发的正常退出
+		return nil
+	}
 	return wrapHubErr("listen", err)
 }
 
+// Shutdown 优雅关闭 Hub：
+// 1. 停止接受新的连接升级请求
+// 2. 停止分发新消息，并等待正在处理及分发器中排队的请求完成
+// 3. 向所有客户端发送 going away 关闭帧，在 ctx 截止前等待发送队列排空
+// 超过 ctx 截止时间仍未关闭的客户端会被强制关闭
+func (wsh *WebSocketHub) Shutdown(ctx context.Context) error {
+	wsh.shuttingDown.Store(true)
+
+	wsh.serverMu.Lock()
+	server := wsh.server
+	wsh.serverMu.Unlock()
+	var serverErr error
+	if server != nil {
+		// 已升级的 WebSocket 连接被 Hijack，不受 http.Server.Shutdown 管理；
+		// 关闭监听失败时仍继续关闭客户端并等待处理中的消息
+		serverErr = wrapHubErr("shutdown: http server", server.Shutdown(ctx))
+	}
+
+	clients := wsh.snapshotBaseClients()
+	for _, client := range clients {
+		client.stopReading()
+	}
+	err := waitUntil(ctx, func() bool {
+		for _, client := range clients {
+			if client.inflight.Load() > 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	for _, client := range clients {
+		client.closeGracefully(websocket.CloseGoingAway, "server shutdown", timeout)
+	}
+
+	if err == nil {
+		err = waitUntil(ctx, func() bool {
+			return wsh.ClientCount() == 0
+		})
+	}
+	if err != nil {
+		// 超时后强制关闭剩余客户端
+		for _, client := range wsh.snapshotBaseClients() {
+			client.Close()
+		}
+		err = wrapHubErr("shutdown: wait clients", err)
+	}
+	if wsh.dispatcher != nil {
+		wsh.dispatcher.Stop()
+	}
+	return errors.Join(serverErr, err)
+}
+
+// waitUntil 轮询等待条件满足，ctx 结束时返回其错误
+func waitUntil(ctx context.Context, cond func() bool) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
 // wrapHubErr 为 Hub 层错误添加上下文
 func wrapHubErr(operation string, err error) error {
 	if err == nil {
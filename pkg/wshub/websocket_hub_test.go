@@ -0,0 +1,589 @@
+package wshub_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wshubtest"
+)
+
+func TestNewHubOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []wshub.ServerOption
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "tls pair", opts: []wshub.ServerOption{wshub.WithTLS("cert.pem", "key.pem")}},
+		{name: "cert without key", opts: []wshub.ServerOption{wshub.WithTLS("cert.pem", "")}, wantErr: true},
+		{name: "key without cert", opts: []wshub.ServerOption{wshub.WithTLS("", "key.pem")}, wantErr: true},
+		{name: "trusted proxies", opts: []wshub.ServerOption{wshub.WithTrustedProxies("10.0.0.0/8", "127.0.0.1")}},
+		{name: "invalid trusted proxy", opts: []wshub.ServerOption{wshub.WithTrustedProxies("not-an-ip")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub(tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHub() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && hub == nil {
+				t.Fatal("NewHub() returned nil hub without error")
+			}
+		})
+	}
+}
+
+func TestHubsAreIndependent(t *testing.T) {
+	newServer := func(name string) (*wshub.WebSocketHub, *wshubtest.Server) {
+		hub, err := wshub.NewHub()
+		if err != nil {
+			t.Fatalf("new hub: %v", err)
+		}
+		hub.OnMessage = func(client wshub.IClient, msg []byte) {
+			_ = client.SendText([]byte(name + ":" + string(msg)))
+		}
+		server := wshubtest.NewServer(hub)
+		t.Cleanup(server.Close)
+		return hub, server
+	}
+	hubA, serverA := newServer("a")
+	hubB, serverB := newServer("b")
+
+	connA, _, err := serverA.Dial("", nil)
+	if err != nil {
+		t.Fatalf("dial a: %v", err)
+	}
+	defer connA.Close()
+	if _, err := serverA.WaitOpen(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hubA.ClientCount(); got != 1 {
+		t.Errorf("hub a ClientCount() = %d, want 1", got)
+	}
+	if got := hubB.ClientCount(); got != 0 {
+		t.Errorf("hub b ClientCount() = %d, want 0", got)
+	}
+	if sent := hubB.Broadcast(wshub.NewTextMessage([]byte("b only"))); sent != 0 {
+		t.Errorf("hub b Broadcast() = %d, want 0", sent)
+	}
+
+	connB, _, err := serverB.Dial("", nil)
+	if err != nil {
+		t.Fatalf("dial b: %v", err)
+	}
+	defer connB.Close()
+	if _, err := serverB.WaitOpen(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	for conn, want := range map[*websocket.Conn]string{connA: "a:ping", connB: "b:ping"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("received %q, want %q", data, want)
+		}
+	}
+}
+
+func TestHandlerOnSharedMux(t *testing.T) {
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	opened := make(chan wshub.IClient, 1)
+	hub.OnOpen = func(client wshub.IClient) { opened <- client }
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws", hub.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("get healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("healthz status = %d, want 200", resp.StatusCode)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("OnOpen not called for the mounted handler")
+	}
+
+	// 对端需要持续读取才能回应关闭帧
+	readErr := make(chan error, 1)
+	go func() {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := conn.ReadMessage()
+		readErr <- err
+	}()
+
+	// Handler 挂载时没有 Hub 自己的 http.Server，Shutdown 只关闭已建立的连接
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if err := <-readErr; !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("read after Shutdown = %v, want going away close", err)
+	}
+	if _, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil); err == nil {
+		t.Error("upgrade accepted after Shutdown")
+	}
+}
+
+func TestAdmissionLimits(t *testing.T) {
+	forwardedFor := func(ip string) http.Header {
+		return http.Header{"X-Forwarded-For": []string{ip}}
+	}
+	tests := []struct {
+		name       string
+		opts       []wshub.ServerOption
+		first      http.Header
+		second     http.Header
+		wantStatus int // 第二个连接被拒绝时的状态码，0 表示允许
+	}{
+		{name: "max connections", opts: []wshub.ServerOption{wshub.WithMaxConnections(1)}, wantStatus: http.StatusServiceUnavailable},
+		{name: "max connections per ip", opts: []wshub.ServerOption{wshub.WithMaxConnectionsPerIP(1)}, wantStatus: http.StatusTooManyRequests},
+		{
+			name:   "per ip behind trusted proxy",
+			opts:   []wshub.ServerOption{wshub.WithMaxConnectionsPerIP(1), wshub.WithTrustedProxies("127.0.0.1")},
+			first:  forwardedFor("203.0.113.1"),
+			second: forwardedFor("203.0.113.2"),
+		},
+		{
+			name:       "same forwarded ip behind trusted proxy",
+			opts:       []wshub.ServerOption{wshub.WithMaxConnectionsPerIP(1), wshub.WithTrustedProxies("127.0.0.1")},
+			first:      forwardedFor("203.0.113.1"),
+			second:     forwardedFor("203.0.113.1"),
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			// 直连方不是可信代理时忽略伪造的 X-Forwarded-For，两个连接按同一个 IP 计数
+			name:       "forwarded ip from untrusted peer",
+			opts:       []wshub.ServerOption{wshub.WithMaxConnectionsPerIP(1)},
+			first:      forwardedFor("203.0.113.1"),
+			second:     forwardedFor("203.0.113.2"),
+			wantStatus: http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub(tt.opts...)
+			if err != nil {
+				t.Fatalf("new hub: %v", err)
+			}
+			server := wshubtest.NewServer(hub)
+			defer server.Close()
+
+			first, _, err := server.Dial("", tt.first)
+			if err != nil {
+				t.Fatalf("dial first: %v", err)
+			}
+			defer first.Close()
+			if _, err := server.WaitOpen(time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			second, resp, err := server.Dial("", tt.second)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("dial second: %v", err)
+				}
+				second.Close()
+				return
+			}
+			if err == nil {
+				second.Close()
+				t.Fatalf("dial second succeeded, want status %d", tt.wantStatus)
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatus {
+				t.Fatalf("dial second response = %v, want status %d", resp, tt.wantStatus)
+			}
+
+			// 连接关闭后释放名额
+			first.Close()
+			if _, err := server.WaitClose(time.Second); err != nil {
+				t.Fatal(err)
+			}
+			third, _, err := server.Dial("", tt.second)
+			if err != nil {
+				t.Fatalf("dial after close: %v", err)
+			}
+			third.Close()
+		})
+	}
+}
+
+func TestAdmissionReleasedOnRejectedHandshake(t *testing.T) {
+	hub, err := wshub.NewHub(wshub.WithMaxConnections(1))
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	hub.SetAuthenticator(func(r *http.Request) (map[string]interface{}, error) {
+		if r.URL.Query().Get("token") == "" {
+			return nil, wshub.NewHTTPError(http.StatusForbidden, "forbidden")
+		}
+		return nil, nil
+	})
+	server := wshubtest.NewServer(hub)
+	defer server.Close()
+
+	// 鉴权失败的握手不占用名额
+	for i := 0; i < 2; i++ {
+		if _, resp, err := server.Dial("", nil); err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("dial without token: err = %v, want status 403", err)
+		}
+	}
+	conn, _, err := server.Dial("token=t", nil)
+	if err != nil {
+		t.Fatalf("dial with token: %v", err)
+	}
+	conn.Close()
+}
+
+func TestAuthenticator(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int // 握手被拒绝时的状态码，0 表示允许升级
+	}{
+		{name: "accepted"},
+		{name: "http error status", err: wshub.NewHTTPError(http.StatusForbidden, "forbidden"), wantStatus: http.StatusForbidden},
+		{name: "wrapped http error", err: fmt.Errorf("auth: %w", wshub.NewHTTPError(http.StatusTooManyRequests, "slow down")), wantStatus: http.StatusTooManyRequests},
+		{name: "plain error", err: errors.New("invalid token"), wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub()
+			if err != nil {
+				t.Fatalf("new hub: %v", err)
+			}
+			hub.SetAuthenticator(func(r *http.Request) (map[string]interface{}, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return map[string]interface{}{"user_id": r.Header.Get("X-User"), "level": 3}, nil
+			})
+			// OnOpen 中即可读取鉴权写入的值
+			userIDs := make(chan string, 1)
+			hub.OnOpen = func(client wshub.IClient) { userIDs <- client.GetContextString("user_id") }
+			server := wshubtest.NewServer(hub)
+			defer server.Close()
+
+			conn, resp, err := server.Dial("", http.Header{"X-User": []string{"u1"}})
+			if tt.wantStatus != 0 {
+				if err == nil {
+					conn.Close()
+					t.Fatalf("dial succeeded, want status %d", tt.wantStatus)
+				}
+				if resp == nil || resp.StatusCode != tt.wantStatus {
+					t.Fatalf("dial response = %v, want status %d", resp, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			client, err := server.WaitOpen(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := <-userIDs; got != "u1" {
+				t.Errorf("user_id in OnOpen = %q, want u1", got)
+			}
+			if got := client.GetContextInt("level"); got != 3 {
+				t.Errorf("level = %d, want 3", got)
+			}
+		})
+	}
+}
+
+// readUntilClose 读取服务端发送的数据帧直到收到关闭帧，返回数据帧和关闭码，未收到关闭帧时关闭码为 0
+func readUntilClose(t *testing.T, conn *websocket.Conn) ([]string, int) {
+	t.Helper()
+	var frames []string
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Errorf("read: %v, want close frame", err)
+			}
+			if closeErr == nil {
+				return frames, 0
+			}
+			return frames, closeErr.Code
+		}
+		frames = append(frames, string(data))
+	}
+}
+
+func TestShutdownDrainsHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		dispatcher *wshub.Dispatcher
+	}{
+		{name: "read goroutine"},
+		{name: "dispatcher", dispatcher: wshub.NewDispatcher(1, wshub.WithOrdered(true))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub()
+			if err != nil {
+				t.Fatalf("new hub: %v", err)
+			}
+			if tt.dispatcher != nil {
+				hub.SetDispatcher(tt.dispatcher)
+			}
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			hub.OnMessage = func(client wshub.IClient, msg []byte) {
+				started <- struct{}{}
+				<-release
+				// 处理结束时发送的消息在关闭帧之前送达
+				for i := 0; i < 3; i++ {
+					_ = client.SendText([]byte(string(msg) + strconv.Itoa(i)))
+				}
+			}
+			server := wshubtest.NewServer(hub)
+			defer server.Close()
+
+			conn, _, err := server.Dial("", nil)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			if _, err := server.WaitOpen(time.Second); err != nil {
+				t.Fatal(err)
+			}
+			for _, msg := range []string{"a", "b"} {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+			<-started
+			// 有分发器时 b 在分发器中排队；没有分发器时读协程阻塞在 a 上，b 尚未读取
+			time.Sleep(50 * time.Millisecond)
+
+			// 客户端持续读取并回复关闭帧，Shutdown 等待关闭握手完成
+			type readResult struct {
+				frames []string
+				code   int
+			}
+			read := make(chan readResult, 1)
+			go func() {
+				frames, code := readUntilClose(t, conn)
+				read <- readResult{frames, code}
+			}()
+
+			shutdown := make(chan error, 1)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+				defer cancel()
+				shutdown <- hub.Shutdown(ctx)
+			}()
+			select {
+			case err := <-shutdown:
+				t.Fatalf("Shutdown returned %v while a handler is running", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+			close(release)
+
+			if err := <-shutdown; err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+			want := []string{"a0", "a1", "a2"}
+			if tt.dispatcher != nil {
+				// 已进入分发器的消息同样处理完成
+				want = append(want, "b0", "b1", "b2")
+			}
+			result := <-read
+			if strings.Join(result.frames, ",") != strings.Join(want, ",") {
+				t.Errorf("frames before close = %v, want %v", result.frames, want)
+			}
+			if result.code != websocket.CloseGoingAway {
+				t.Errorf("close code = %d, want %d", result.code, websocket.CloseGoingAway)
+			}
+			if got := hub.ClientCount(); got != 0 {
+				t.Errorf("ClientCount() = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestShutdownForceClosesAfterTimeout(t *testing.T) {
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	hub.OnMessage = func(client wshub.IClient, msg []byte) {
+		close(started)
+		<-release
+	}
+	server := wshubtest.NewServer(hub)
+	defer server.Close()
+
+	conn, _, err := server.Dial("", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := server.WaitOpen(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("stuck")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	err = hub.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Shutdown took %v after ctx expired", elapsed)
+	}
+	// 超时后强制关闭，不再等待处理中的消息
+	if _, err := server.WaitClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := hub.ClientCount(); got != 0 {
+		t.Errorf("ClientCount() = %d, want 0", got)
+	}
+}
+
+func TestShutdownClosesConnectionsUpgradedDuringShutdown(t *testing.T) {
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	// 握手停在鉴权阶段时开始关闭，Shutdown 获取的客户端快照中没有这个连接
+	authenticating := make(chan struct{})
+	proceed := make(chan struct{})
+	hub.SetAuthenticator(func(*http.Request) (map[string]interface{}, error) {
+		close(authenticating)
+		<-proceed
+		return nil, nil
+	})
+	var opened atomic.Bool
+	hub.OnOpen = func(wshub.IClient) { opened.Store(true) }
+	server := wshubtest.NewServer(hub)
+	defer server.Close()
+
+	type dialResult struct {
+		conn *websocket.Conn
+		err  error
+	}
+	dialed := make(chan dialResult, 1)
+	go func() {
+		conn, _, err := server.Dial("", nil)
+		dialed <- dialResult{conn, err}
+	}()
+	<-authenticating
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	close(proceed)
+
+	result := <-dialed
+	if result.err != nil {
+		t.Fatalf("dial: %v", result.err)
+	}
+	defer result.conn.Close()
+	if _, code := readUntilClose(t, result.conn); code != websocket.CloseGoingAway {
+		t.Errorf("close code = %d, want %d", code, websocket.CloseGoingAway)
+	}
+	if got := hub.ClientCount(); got != 0 {
+		t.Errorf("ClientCount() = %d, want 0", got)
+	}
+	if opened.Load() {
+		t.Error("OnOpen called for a connection closed during shutdown")
+	}
+
+	// 关闭后的升级请求直接拒绝
+	if _, resp, err := server.Dial("", nil); err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("dial after shutdown: err = %v, want status 503", err)
+	}
+}
+
+func TestOnDispatchError(t *testing.T) {
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	dispatcher := wshub.NewDispatcher(1)
+	dispatcher.Stop()
+	hub.SetDispatcher(dispatcher)
+	var handled atomic.Bool
+	hub.OnMessage = func(wshub.IClient, []byte) { handled.Store(true) }
+	failed := make(chan string, 1)
+	hub.OnDispatchError = func(_ wshub.IClient, msg []byte, err error) {
+		if err == nil {
+			t.Error("OnDispatchError called with nil error")
+		}
+		failed <- string(msg)
+	}
+	server := wshubtest.NewServer(hub)
+	defer server.Close()
+
+	conn, _, err := server.Dial("", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := server.WaitOpen(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("req")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	select {
+	case msg := <-failed:
+		if msg != "req" {
+			t.Errorf("OnDispatchError msg = %q, want req", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnDispatchError not called")
+	}
+	if handled.Load() {
+		t.Error("OnMessage called for a message that failed to dispatch")
+	}
+}
@@ -0,0 +1,115 @@
+package wshub
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// RateLimitAction 触发限流后的处理方式
+type RateLimitAction int
+
+const (
+	RateLimitDrop       RateLimitAction = iota // 丢弃消息
+	RateLimitReply                             // 丢弃消息，并通过 OnRateLimited 回调回复错误帧
+	RateLimitDisconnect                        // 断开连接
+)
+
+// RateLimit 令牌桶参数
+type RateLimit struct {
+	Rate  float64 // 每秒补充的令牌数，<= 0 表示不限制
+	Burst int     // 桶容量，即允许的突发消息数
+}
+
+// RateLimitConfig 客户端入站消息限流配置
+type RateLimitConfig struct {
+	Limit     RateLimit               // 客户端整体限流
+	Action    RateLimitAction         // 触发限流后的处理方式
+	CloseCode int                     // RateLimitDisconnect 时发送的关闭码，默认 ClosePolicyViolation
+	Classify  func(msg []byte) string // 消息分类函数，例如按协议类型分类，为空时只做整体限流
+	KeyLimits map[string]RateLimit    // 各分类的独立限流参数，与整体限流同时生效
+}
+
+// WithRateLimit 设置客户端入站消息限流
+func WithRateLimit(cfg RateLimitConfig) ClientOption {
+	return func(config *ClientConfig) {
+		if cfg.CloseCode == 0 {
+			cfg.CloseCode = websocket.ClosePolicyViolation
+		}
+		config.rateLimit = &cfg
+	}
+}
+
+// TokenBucket 令牌桶限流器，可并发使用
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 创建令牌桶，初始为满桶
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow 尝试消耗一个令牌，令牌不足时返回 false
+func (b *TokenBucket) Allow() bool {
+	if b.rate <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiter 单个客户端的限流器，由读协程独占使用
+type rateLimiter struct {
+	cfg     *RateLimitConfig
+	global  *TokenBucket
+	buckets map[string]*TokenBucket
+}
+
+func newRateLimiter(cfg *RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		cfg:     cfg,
+		global:  NewTokenBucket(cfg.Limit.Rate, cfg.Limit.Burst),
+		buckets: make(map[string]*TokenBucket),
+	}
+}
+
+// allow 检查消息是否允许通过，先检查分类限流再检查整体限流
+func (l *rateLimiter) allow(msg []byte) bool {
+	if l.cfg.Classify != nil && len(l.cfg.KeyLimits) > 0 {
+		key := l.cfg.Classify(msg)
+		if limit, ok := l.cfg.KeyLimits[key]; ok {
+			bucket, ok := l.buckets[key]
+			if !ok {
+				bucket = NewTokenBucket(limit.Rate, limit.Burst)
+				l.buckets[key] = bucket
+			}
+			if !bucket.Allow() {
+				return false
+			}
+		}
+	}
+	return l.global.Allow()
+}
@@ -0,0 +1,82 @@
+package wshub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		elapsed time.Duration // 耗尽初始令牌后经过的时间
+		want    int           // 经过 elapsed 后允许通过的消息数
+	}{
+		{name: "no refill", rate: 1, burst: 3, elapsed: 0, want: 0},
+		{name: "partial refill", rate: 10, burst: 5, elapsed: 250 * time.Millisecond, want: 2},
+		{name: "refill capped at burst", rate: 100, burst: 3, elapsed: time.Minute, want: 3},
+		{name: "burst below one", rate: 1, burst: 0, elapsed: 2 * time.Second, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := NewTokenBucket(tt.rate, tt.burst)
+			initial := max(tt.burst, 1)
+			for i := 0; i < initial; i++ {
+				if !bucket.Allow() {
+					t.Fatalf("initial token %d rejected", i)
+				}
+			}
+			// 回拨上次补充时间模拟时间流逝，减去少量余量避免测试执行耗时带来额外令牌
+			bucket.mu.Lock()
+			bucket.tokens = 0
+			bucket.last = time.Now().Add(-tt.elapsed + time.Millisecond)
+			bucket.mu.Unlock()
+
+			got := 0
+			for bucket.Allow() {
+				got++
+				if got > initial {
+					break
+				}
+			}
+			if got != tt.want {
+				t.Errorf("allowed %d after %s, want %d", got, tt.elapsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketUnlimited(t *testing.T) {
+	bucket := NewTokenBucket(0, 1)
+	for i := 0; i < 100; i++ {
+		if !bucket.Allow() {
+			t.Fatalf("message %d rejected with rate 0", i)
+		}
+	}
+}
+
+func TestRateLimiterKeyLimits(t *testing.T) {
+	limiter := newRateLimiter(&RateLimitConfig{
+		Limit:    RateLimit{Rate: 0.001, Burst: 3},
+		Classify: func(msg []byte) string { return string(msg) },
+		KeyLimits: map[string]RateLimit{
+			"login": {Rate: 0.001, Burst: 1},
+		},
+	})
+	steps := []struct {
+		msg  string
+		want bool
+	}{
+		{msg: "login", want: true},
+		{msg: "login", want: false}, // 分类令牌耗尽，不消耗整体令牌
+		{msg: "chat", want: true},
+		{msg: "chat", want: true},
+		{msg: "chat", want: false}, // 整体令牌耗尽
+	}
+	for i, step := range steps {
+		if got := limiter.allow([]byte(step.msg)); got != step.want {
+			t.Errorf("step %d allow(%q) = %v, want %v", i, step.msg, got, step.want)
+		}
+	}
+}
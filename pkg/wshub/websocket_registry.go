@@ -0,0 +1,83 @@
+package wshub
+
+// addClient 登记在线客户端
+func (wsh *WebSocketHub) addClient(baseClient *Client, client IClient) {
+	wsh.clientsMu.Lock()
+	defer wsh.clientsMu.Unlock()
+	wsh.clients[baseClient] = client
+}
+
+// removeClient 移除已关闭的客户端
+func (wsh *WebSocketHub) removeClient(baseClient *Client) {
+	wsh.clientsMu.Lock()
+	defer wsh.clientsMu.Unlock()
+	delete(wsh.clients, baseClient)
+}
+
+// snapshotClients 复制当前在线客户端列表，避免在持锁期间执行发送等耗时操作
+func (wsh *WebSocketHub) snapshotClients() []IClient {
+	wsh.clientsMu.RLock()
+	defer wsh.clientsMu.RUnlock()
+	clients := make([]IClient, 0, len(wsh.clients))
+	for _, client := range wsh.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// snapshotBaseClients 复制当前在线的底层客户端列表
+func (wsh *WebSocketHub) snapshotBaseClients() []*Client {
+	wsh.clientsMu.RLock()
+	defer wsh.clientsMu.RUnlock()
+	clients := make([]*Client, 0, len(wsh.clients))
+	for baseClient := range wsh.clients {
+		clients = append(clients, baseClient)
+	}
+	return clients
+}
+
+// ClientCount 获取当前在线客户端数量
+func (wsh *WebSocketHub) ClientCount() int {
+	wsh.clientsMu.RLock()
+	defer wsh.clientsMu.RUnlock()
+	return len(wsh.clients)
+}
+
+// ForEach 遍历所有在线客户端，fn 返回 false 时停止遍历
+func (wsh *WebSocketHub) ForEach(fn func(client IClient) bool) {
+	for _, client := range wsh.snapshotClients() {
+		if !fn(client) {
+			return
+		}
+	}
+}
+
+// SendTo 向满足 filter 条件的在线客户端发送消息，返回成功放入发送队列的客户端数量
+// 开启会话恢复时，满足条件且处于断线宽限期内的会话也会缓存该消息
+func (wsh *WebSocketHub) SendTo(filter func(client IClient) bool, msg *Message) int {
+	sent := 0
+	clients := wsh.snapshotClients()
+	online := make(map[*Client]struct{}, len(clients))
+	for _, client := range clients {
+		online[client.GetBaseClient()] = struct{}{}
+		if filter != nil && !filter(client) {
+			continue
+		}
+		if err := sendMessage(client, msg); err != nil {
+			if wsh.OnError != nil {
+				wsh.OnError(client, wrapHubErr("send to client", err))
+			}
+			continue
+		}
+		sent++
+	}
+	sent += wsh.bufferDetached(func(client IClient, _ []string) bool {
+		return filter == nil || filter(client)
+	}, online, msg)
+	return sent
+}
+
+// Broadcast 向所有在线客户端发送消息，返回成功放入发送队列的客户端数量
+func (wsh *WebSocketHub) Broadcast(msg *Message) int {
+	return wsh.SendTo(nil, msg)
+}
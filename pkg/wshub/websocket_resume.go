@@ -0,0 +1,345 @@
+package wshub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 会话恢复相关的握手参数与响应头
+const (
+	ResumeTokenParam   = "resume_token"    // 查询参数：上一次连接的恢复令牌
+	LastSeqParam       = "last_seq"        // 查询参数：客户端在该会话中已收到的数据帧数量
+	ResumeTokenHeader  = "X-Resume-Token"  // 请求/响应头：恢复令牌
+	LastSeqHeader      = "X-Resume-Seq"    // 请求头：客户端在该会话中已收到的数据帧数量
+	ResumeStatusHeader = "X-Resume-Status" // 响应头：resumed 表示已恢复，new 表示新会话
+)
+
+// WithSessionResume 开启会话恢复
+// 每个连接分配一个恢复令牌，并保留最近 bufferSize 个已发送的数据帧；
+// 连接异常断开后 grace 时间内携带令牌重连即可恢复会话，断开期间发送的消息及客户端未收到的消息会按顺序补发
+func WithSessionResume(grace time.Duration, bufferSize int) ServerOption {
+	return func(cfg *ServerConfig) {
+		cfg.ResumeGrace = grace
+		cfg.ResumeBufferSize = bufferSize
+	}
+}
+
+// resumeManager 管理所有可恢复的会话
+type resumeManager struct {
+	mu       sync.Mutex
+	sessions map[string]*resumeSession
+}
+
+// resumeSession 可恢复的会话
+// 序号从 1 开始，按数据帧写入连接（或断开期间缓存）的顺序分配
+type resumeSession struct {
+	manager    *resumeManager
+	token      string
+	grace      time.Duration
+	bufferSize int
+	mu         sync.Mutex
+	seq        uint64     // 最后分配的序号
+	frames     []*Message // 最近的数据帧，按序号递增
+	client     *Client    // 当前绑定的底层客户端
+	appClient  IClient    // 当前绑定的业务客户端，断开期间用于按条件推送时的过滤
+	appBase    *Client    // appClient 对应的底层客户端，认领后仍保留，用于继承上下文
+	detached   bool
+	topics     []string // 断开时所在的主题，恢复后重新加入
+	timer      *time.Timer
+}
+
+func newResumeManager() *resumeManager {
+	return &resumeManager{sessions: make(map[string]*resumeSession)}
+}
+
+// create 创建新的会话
+func (m *resumeManager) create(grace time.Duration, bufferSize int) (*resumeSession, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	s := &resumeSession{
+		manager:    m,
+		token:      hex.EncodeToString(buf),
+		grace:      grace,
+		bufferSize: bufferSize,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.token] = s
+	return s, nil
+}
+
+// claim 认领处于断开状态的会话，返回需要补发的数据帧
+// 会话不存在、已过期，或客户端缺失的数据帧已不在缓存中时返回 false
+func (m *resumeManager) claim(token string, lastSeq uint64, maxReplay int) (*resumeSession, []*Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[token]
+	if !ok {
+		return nil, nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.detached || lastSeq > s.seq {
+		return nil, nil, false
+	}
+	var replay []*Message
+	for _, frame := range s.frames {
+		if frame.seq > lastSeq {
+			replay = append(replay, frame)
+		}
+	}
+	if uint64(len(replay)) != s.seq-lastSeq || len(replay) > maxReplay {
+		// 缺失的数据帧已被淘汰，或补发数量超过发送队列容量，无法完整恢复
+		return nil, nil, false
+	}
+
+	s.detached = false
+	s.client = nil
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	return s, replay, true
+}
+
+// remove 移除会话
+func (m *resumeManager) remove(s *resumeSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[s.token] == s {
+		delete(m.sessions, s.token)
+	}
+}
+
+// expire 宽限期结束后移除仍处于断开状态的会话
+func (m *resumeManager) expire(s *resumeSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.detached && m.sessions[s.token] == s {
+		delete(m.sessions, s.token)
+	}
+}
+
+// detachedSessions 获取所有处于断开状态的会话
+func (m *resumeManager) detachedSessions() []*resumeSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []*resumeSession
+	for _, s := range m.sessions {
+		s.mu.Lock()
+		if s.detached {
+			sessions = append(sessions, s)
+		}
+		s.mu.Unlock()
+	}
+	return sessions
+}
+
+// attach 绑定新的客户端
+func (s *resumeSession) attach(baseClient *Client, client IClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.client = baseClient
+	s.appClient = client
+	s.appBase = baseClient
+	s.topics = nil
+}
+
+// detach 客户端断开时调用，可恢复时进入宽限期，否则直接移除会话
+func (s *resumeSession) detach(baseClient *Client, resumable bool) {
+	s.mu.Lock()
+	if s.client != baseClient {
+		s.mu.Unlock()
+		return
+	}
+	if resumable {
+		s.detached = true
+		s.timer = time.AfterFunc(s.grace, func() {
+			s.manager.expire(s)
+		})
+	}
+	s.mu.Unlock()
+
+	if !resumable {
+		s.manager.remove(s)
+	}
+}
+
+// record 记录已写入连接的数据帧，客户端已切换时忽略
+func (s *resumeSession) record(baseClient *Client, msg *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == baseClient && !s.detached {
+		s.appendLocked(msg)
+	}
+}
+
+// bufferIfDetached 会话处于断开状态时缓存数据帧，等待恢复后补发
+// baseClient 为 nil 时不校验发送方，用于 Hub 按条件推送
+func (s *resumeSession) bufferIfDetached(baseClient *Client, msg *Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.detached || (baseClient != nil && s.client != baseClient) {
+		return false
+	}
+	s.appendLocked(msg)
+	return true
+}
+
+// appendLocked 分配序号并放入缓存，超出容量时淘汰最旧的数据帧，调用方需持有 mu
+func (s *resumeSession) appendLocked(msg *Message) {
+	s.seq++
+	s.frames = append(s.frames, &Message{Type: msg.Type, Data: msg.Data, seq: s.seq})
+	if len(s.frames) > s.bufferSize {
+		s.frames = append(s.frames[:0:0], s.frames[len(s.frames)-s.bufferSize:]...)
+	}
+}
+
+// previous 获取断开前绑定的客户端及其所在的主题
+func (s *resumeSession) previous() (IClient, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appClient, s.topics
+}
+
+// previousBase 获取断开前绑定的底层客户端，不依赖业务客户端的 GetBaseClient 实现
+func (s *resumeSession) previousBase() *Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appBase
+}
+
+// saveTopics 记录断开时所在的主题，会话已被新连接认领时忽略
+func (s *resumeSession) saveTopics(baseClient *Client, topics []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == baseClient {
+		s.topics = topics
+	}
+}
+
+// resumeHandshake 握手阶段准备好的会话
+type resumeHandshake struct {
+	session *resumeSession
+	replay  []*Message // 需要补发的数据帧
+	resumed bool       // 是否恢复了旧会话
+}
+
+// abandon 连接建立失败时释放会话：恢复的旧会话重新进入宽限期，新会话直接移除
+func (h *resumeHandshake) abandon() {
+	if h != nil {
+		h.session.detach(nil, h.resumed)
+	}
+}
+
+// resumeParams 从握手请求中读取恢复令牌和已收到的数据帧数量
+func resumeParams(r *http.Request) (string, uint64) {
+	token := r.URL.Query().Get(ResumeTokenParam)
+	if token == "" {
+		token = r.Header.Get(ResumeTokenHeader)
+	}
+	seq := r.URL.Query().Get(LastSeqParam)
+	if seq == "" {
+		seq = r.Header.Get(LastSeqHeader)
+	}
+	lastSeq, _ := strconv.ParseUint(seq, 10, 64)
+	return token, lastSeq
+}
+
+// prepareResume 握手阶段准备会话：尝试恢复旧会话，否则创建新会话，并写入响应头
+// 未开启会话恢复时返回 nil
+func (wsh *WebSocketHub) prepareResume(r *http.Request, header http.Header) (*resumeHandshake, error) {
+	if wsh.ResumeGrace <= 0 {
+		return nil, nil
+	}
+
+	handshake := &resumeHandshake{}
+	token, lastSeq := resumeParams(r)
+	if token != "" {
+		// 补发的数据帧需要一次性放入新连接的发送队列
+		maxReplay := newClientConfig(wsh.clientOptions...).chanLength
+		handshake.session, handshake.replay, handshake.resumed = wsh.resumes.claim(token, lastSeq, maxReplay)
+	}
+	if !handshake.resumed {
+		session, err := wsh.resumes.create(wsh.ResumeGrace, wsh.ResumeBufferSize)
+		if err != nil {
+			return nil, err
+		}
+		handshake.session = session
+	}
+
+	status := "new"
+	if handshake.resumed {
+		status = "resumed"
+	}
+	header.Set(ResumeTokenHeader, handshake.session.token)
+	header.Set(ResumeStatusHeader, status)
+	return handshake, nil
+}
+
+// restoreSession 将会话绑定到新连接，恢复旧会话时继承上下文并补发数据帧，返回需要重新加入的主题
+func (wsh *WebSocketHub) restoreSession(handshake *resumeHandshake, baseClient *Client, client IClient) []string {
+	var topics []string
+	if handshake.resumed {
+		_, topics = handshake.session.previous()
+		if prevBase := handshake.session.previousBase(); prevBase != nil {
+			prevBase.ctxMu.Lock()
+			ctx := prevBase.ctx
+			prevBase.ctxMu.Unlock()
+			baseClient.ctxMu.Lock()
+			baseClient.ctx = ctx
+			baseClient.ctxMu.Unlock()
+		}
+		// 尚未启动写协程，容量已在认领时校验
+		for _, frame := range handshake.replay {
+			baseClient.sendBuffer <- frame
+		}
+	}
+
+	baseClient.resume = handshake.session
+	baseClient.resumed = handshake.resumed
+	handshake.session.attach(baseClient, client)
+	return topics
+}
+
+// bufferDetached 为断开期间满足条件的会话缓存消息，skip 中的客户端已通过在线连接处理
+func (wsh *WebSocketHub) bufferDetached(filter func(client IClient, topics []string) bool, skip map[*Client]struct{}, msg *Message) int {
+	if wsh.ResumeGrace <= 0 {
+		return 0
+	}
+	buffered := 0
+	for _, s := range wsh.resumes.detachedSessions() {
+		previous, topics := s.previous()
+		if previous == nil {
+			continue
+		}
+		if _, ok := skip[previous.GetBaseClient()]; ok {
+			continue
+		}
+		if filter(previous, topics) && s.bufferIfDetached(nil, msg) {
+			buffered++
+		}
+	}
+	return buffered
+}
+
+// ResumeToken 获取客户端的会话恢复令牌，未开启会话恢复时返回空字符串
+func (c *Client) ResumeToken() string {
+	if c.resume == nil {
+		return ""
+	}
+	return c.resume.token
+}
+
+// Resumed 客户端是否由断线重连恢复
+func (c *Client) Resumed() bool {
+	return c.resumed
+}
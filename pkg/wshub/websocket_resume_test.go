@@ -0,0 +1,154 @@
+package wshub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newDetachedSession 创建已记录 sent 个数据帧后断开的会话
+func newDetachedSession(t *testing.T, m *resumeManager, bufferSize int, sent int) *resumeSession {
+	t.Helper()
+	s, err := m.create(time.Minute, bufferSize)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	base := &Client{}
+	s.attach(base, base)
+	for i := 1; i <= sent; i++ {
+		s.record(base, NewTextMessage([]byte{byte('0' + i)}))
+	}
+	s.detach(base, true)
+	t.Cleanup(func() { s.timer.Stop() })
+	return s
+}
+
+func TestResumeClaim(t *testing.T) {
+	tests := []struct {
+		name       string
+		bufferSize int
+		sent       int // 断开前写入连接的数据帧数量
+		buffered   int // 断开期间缓存的数据帧数量
+		lastSeq    uint64
+		maxReplay  int
+		wantOK     bool
+		wantReplay string
+	}{
+		{name: "nothing missed", bufferSize: 4, sent: 3, lastSeq: 3, maxReplay: 8, wantOK: true},
+		{name: "replay unreceived frames", bufferSize: 4, sent: 3, lastSeq: 1, maxReplay: 8, wantOK: true, wantReplay: "23"},
+		{name: "replay frames buffered while detached", bufferSize: 4, sent: 2, buffered: 2, lastSeq: 2, maxReplay: 8, wantOK: true, wantReplay: "34"},
+		{name: "missing frames evicted", bufferSize: 2, sent: 4, lastSeq: 1, maxReplay: 8},
+		{name: "client ahead of server", bufferSize: 4, sent: 2, lastSeq: 3, maxReplay: 8},
+		{name: "replay exceeds send queue", bufferSize: 4, sent: 4, lastSeq: 0, maxReplay: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newResumeManager()
+			s := newDetachedSession(t, m, tt.bufferSize, tt.sent)
+			for i := tt.sent + 1; i <= tt.sent+tt.buffered; i++ {
+				if !s.bufferIfDetached(nil, NewTextMessage([]byte{byte('0' + i)})) {
+					t.Fatalf("frame %d not buffered while detached", i)
+				}
+			}
+
+			claimed, replay, ok := m.claim(s.token, tt.lastSeq, tt.maxReplay)
+			if ok != tt.wantOK {
+				t.Fatalf("claim ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if claimed != nil || replay != nil {
+					t.Error("failed claim returned a session")
+				}
+				return
+			}
+			got := ""
+			for i, frame := range replay {
+				got += string(frame.Data)
+				if want := tt.lastSeq + uint64(i) + 1; frame.seq != want {
+					t.Errorf("replay[%d].seq = %d, want %d", i, frame.seq, want)
+				}
+			}
+			if got != tt.wantReplay {
+				t.Errorf("replay = %q, want %q", got, tt.wantReplay)
+			}
+			// 会话已被认领，不能再次恢复
+			if _, _, ok := m.claim(s.token, tt.lastSeq, tt.maxReplay); ok {
+				t.Error("session claimed twice")
+			}
+		})
+	}
+}
+
+func TestResumeClaimUnknownOrExpired(t *testing.T) {
+	m := newResumeManager()
+	if _, _, ok := m.claim("missing", 0, 8); ok {
+		t.Error("claimed an unknown token")
+	}
+
+	s := newDetachedSession(t, m, 4, 1)
+	m.expire(s)
+	if _, _, ok := m.claim(s.token, 1, 8); ok {
+		t.Error("claimed an expired session")
+	}
+}
+
+// wrappedClient 不暴露底层客户端的业务客户端
+type wrappedClient struct {
+	IClient
+}
+
+func (wrappedClient) GetBaseClient() *Client { return nil }
+
+func TestRestoreSessionInheritsContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		wrap   func(base *Client) IClient
+		topics []string
+	}{
+		{name: "base client", wrap: func(base *Client) IClient { return base }, topics: []string{"lab"}},
+		{name: "wrapper without base client", wrap: func(base *Client) IClient { return wrappedClient{base} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newResumeManager()
+			s, err := m.create(time.Minute, 4)
+			if err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			previous := &Client{ctx: context.Background()}
+			previous.SetContextValue("user_id", "u1")
+			s.attach(previous, tt.wrap(previous))
+			s.record(previous, NewTextMessage([]byte("sent")))
+			s.saveTopics(previous, tt.topics)
+			s.detach(previous, true)
+			s.bufferIfDetached(nil, NewTextMessage([]byte("missed")))
+			defer s.timer.Stop()
+
+			claimed, replay, ok := m.claim(s.token, 1, 8)
+			if !ok {
+				t.Fatal("claim failed")
+			}
+			wsh := &WebSocketHub{}
+			next := &Client{ctx: context.Background(), sendBuffer: make(chan *Message, 8)}
+			topics := wsh.restoreSession(&resumeHandshake{session: claimed, replay: replay, resumed: true}, next, next)
+
+			if got := next.GetContextString("user_id"); got != "u1" {
+				t.Errorf("inherited user_id = %q, want %q", got, "u1")
+			}
+			if len(topics) != len(tt.topics) {
+				t.Errorf("topics = %v, want %v", topics, tt.topics)
+			}
+			if !next.Resumed() || next.ResumeToken() != s.token {
+				t.Error("resumed client not bound to the session")
+			}
+			select {
+			case frame := <-next.sendBuffer:
+				if string(frame.Data) != "missed" {
+					t.Errorf("replayed %q, want %q", frame.Data, "missed")
+				}
+			default:
+				t.Error("missed frame not queued for replay")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package wshub
+
+// Join 将客户端加入指定主题（房间），已关闭的客户端会被忽略
+func (wsh *WebSocketHub) Join(client IClient, topic string) {
+	baseClient := client.GetBaseClient()
+	wsh.roomsMu.Lock()
+	defer wsh.roomsMu.Unlock()
+	// 在持有 roomsMu 时检查关闭状态，保证与关闭时的 leaveAllRooms 互斥，避免残留成员
+	if baseClient.isClosed() {
+		return
+	}
+
+	members, ok := wsh.rooms[topic]
+	if !ok {
+		members = make(map[*Client]IClient)
+		wsh.rooms[topic] = members
+	}
+	members[baseClient] = client
+
+	topics, ok := wsh.clientRooms[baseClient]
+	if !ok {
+		topics = make(map[string]struct{})
+		wsh.clientRooms[baseClient] = topics
+	}
+	topics[topic] = struct{}{}
+}
+
+// Leave 将客户端移出指定主题
+func (wsh *WebSocketHub) Leave(client IClient, topic string) {
+	baseClient := client.GetBaseClient()
+	wsh.roomsMu.Lock()
+	defer wsh.roomsMu.Unlock()
+	wsh.leaveLocked(baseClient, topic)
+}
+
+// LeaveAll 将客户端移出其加入的所有主题
+func (wsh *WebSocketHub) LeaveAll(client IClient) {
+	wsh.leaveAllRooms(client.GetBaseClient())
+}
+
+// Topics 获取客户端当前加入的主题列表
+func (wsh *WebSocketHub) Topics(client IClient) []string {
+	wsh.roomsMu.RLock()
+	defer wsh.roomsMu.RUnlock()
+	topics := make([]string, 0, len(wsh.clientRooms[client.GetBaseClient()]))
+	for topic := range wsh.clientRooms[client.GetBaseClient()] {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// RoomMembers 获取主题下的所有客户端
+func (wsh *WebSocketHub) RoomMembers(topic string) []IClient {
+	wsh.roomsMu.RLock()
+	defer wsh.roomsMu.RUnlock()
+	members := make([]IClient, 0, len(wsh.rooms[topic]))
+	for _, client := range wsh.rooms[topic] {
+		members = append(members, client)
+	}
+	return members
+}
+
+// Publish 向主题下的所有客户端发送消息，返回成功放入发送队列的客户端数量
+// 开启会话恢复时，断开前加入了该主题且处于宽限期内的会话也会缓存该消息
+func (wsh *WebSocketHub) Publish(topic string, msg *Message) int {
+	sent := 0
+	members := wsh.RoomMembers(topic)
+	joined := make(map[*Client]struct{}, len(members))
+	for _, client := range members {
+		joined[client.GetBaseClient()] = struct{}{}
+		if err := sendMessage(client, msg); err != nil {
+			if wsh.OnError != nil {
+				wsh.OnError(client, wrapHubErr("publish "+topic, err))
+			}
+			continue
+		}
+		sent++
+	}
+	sent += wsh.bufferDetached(func(_ IClient, topics []string) bool {
+		for _, t := range topics {
+			if t == topic {
+				return true
+			}
+		}
+		return false
+	}, joined, msg)
+	return sent
+}
+
+// leaveAllRooms 清理客户端的所有主题成员关系，客户端关闭时调用
+func (wsh *WebSocketHub) leaveAllRooms(baseClient *Client) {
+	wsh.roomsMu.Lock()
+	defer wsh.roomsMu.Unlock()
+	for topic := range wsh.clientRooms[baseClient] {
+		wsh.leaveLocked(baseClient, topic)
+	}
+}
+
+// leaveLocked 移出主题，调用方需持有 roomsMu
+func (wsh *WebSocketHub) leaveLocked(baseClient *Client, topic string) {
+	if members, ok := wsh.rooms[topic]; ok {
+		delete(members, baseClient)
+		if len(members) == 0 {
+			delete(wsh.rooms, topic)
+		}
+	}
+	if topics, ok := wsh.clientRooms[baseClient]; ok {
+		delete(topics, topic)
+		if len(topics) == 0 {
+			delete(wsh.clientRooms, baseClient)
+		}
+	}
+}
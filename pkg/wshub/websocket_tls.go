@@ -0,0 +1,101 @@
+package wshub
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval 检查证书文件是否变更的最小间隔
+const certCheckInterval = 30 * time.Second
+
+// certReloader 证书热加载器
+// 在 TLS 握手时按间隔检查证书文件的修改时间，文件变更后重新加载证书，无需重启服务
+type certReloader struct {
+	certFile    string
+	keyFile     string
+	onError     func(err error)
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+// newCertReloader 创建证书热加载器，首次加载失败时返回错误
+func newCertReloader(certFile, keyFile string, onError func(err error)) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		onError:  onError,
+	}
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certModTime, keyModTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate 供 tls.Config 使用，返回当前证书
+func (r *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// maybeReload 证书文件变更时重新加载，加载失败时继续使用旧证书
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < certCheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	oldCertModTime, oldKeyModTime := r.certModTime, r.keyModTime
+	r.mu.Unlock()
+
+	certModTime, keyModTime, err := r.modTimes()
+	if err == nil && certModTime.Equal(oldCertModTime) && keyModTime.Equal(oldKeyModTime) {
+		return
+	}
+	if err == nil {
+		err = r.load(certModTime, keyModTime)
+	}
+	if err != nil && r.onError != nil {
+		r.onError(err)
+	}
+}
+
+// load 加载证书并记录文件修改时间
+func (r *certReloader) load(certModTime, keyModTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	r.lastCheck = time.Now()
+	return nil
+}
+
+// modTimes 获取证书和私钥文件的修改时间
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat cert file: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat key file: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
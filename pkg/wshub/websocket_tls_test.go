@@ -0,0 +1,162 @@
+package wshub
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair 生成 CommonName 为 name 的自签名证书写入 certFile / keyFile，并将文件修改时间设为 modTime
+func writeKeyPair(t *testing.T, certFile, keyFile, name string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+}
+
+// writeFile 写入文件并设置修改时间
+func writeFile(t *testing.T, name string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatalf("chtimes %s: %v", name, err)
+	}
+}
+
+// servedName 获取热加载器当前返回证书的 CommonName
+func servedName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// expireCheck 使下一次握手跳过检查间隔，立即检查证书文件
+func expireCheck(r *certReloader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = time.Now().Add(-certCheckInterval)
+}
+
+func TestNewCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	garbage := filepath.Join(dir, "garbage.pem")
+	writeKeyPair(t, certFile, keyFile, "v1", time.Now())
+	writeFile(t, garbage, []byte("not a pem"), time.Now())
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "valid pair", certFile: certFile, keyFile: keyFile},
+		{name: "missing cert", certFile: filepath.Join(dir, "missing.pem"), keyFile: keyFile, wantErr: true},
+		{name: "missing key", certFile: certFile, keyFile: filepath.Join(dir, "missing.pem"), wantErr: true},
+		{name: "invalid cert", certFile: garbage, keyFile: keyFile, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newCertReloader(tt.certFile, tt.keyFile, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCertReloader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && servedName(t, r) != "v1" {
+				t.Errorf("served certificate is not the loaded one")
+			}
+		})
+	}
+}
+
+func TestCertReloaderReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	modTime := time.Now().Add(-time.Hour)
+	writeKeyPair(t, certFile, keyFile, "v1", modTime)
+
+	var errs []error
+	r, err := newCertReloader(certFile, keyFile, func(err error) { errs = append(errs, err) })
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	if got := servedName(t, r); got != "v1" {
+		t.Fatalf("served %q, want v1", got)
+	}
+
+	// 检查间隔内不检查文件
+	writeKeyPair(t, certFile, keyFile, "v2", modTime.Add(time.Minute))
+	if got := servedName(t, r); got != "v1" {
+		t.Errorf("served %q within check interval, want v1", got)
+	}
+	expireCheck(r)
+	if got := servedName(t, r); got != "v2" {
+		t.Errorf("served %q after check interval, want v2", got)
+	}
+
+	// 写入损坏的证书后继续使用旧证书，并报告错误
+	writeFile(t, certFile, []byte("broken"), modTime.Add(2*time.Minute))
+	expireCheck(r)
+	if got := servedName(t, r); got != "v2" {
+		t.Errorf("served %q after broken rewrite, want v2", got)
+	}
+	if len(errs) != 1 {
+		t.Errorf("reported errors = %v, want 1", errs)
+	}
+
+	// 删除文件同样保留旧证书
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+	expireCheck(r)
+	if got := servedName(t, r); got != "v2" {
+		t.Errorf("served %q after key removed, want v2", got)
+	}
+	if len(errs) != 2 {
+		t.Errorf("reported errors = %v, want 2", errs)
+	}
+
+	// 修复后加载新证书
+	writeKeyPair(t, certFile, keyFile, "v3", modTime.Add(3*time.Minute))
+	expireCheck(r)
+	if got := servedName(t, r); got != "v3" {
+		t.Errorf("served %q after repair, want v3", got)
+	}
+	if len(errs) != 2 {
+		t.Errorf("reported errors = %v, want 2", errs)
+	}
+}
@@ -0,0 +1,381 @@
+// Package wsclient wshub 的 Go 客户端，实现与小程序端相同的连接协议：
+// 断线自动重连（指数退避）、响应服务端 Ping、会话恢复，
+// 并在 ProtocolClient 中提供 model.BaseRequest / model.BaseResponse 的请求响应封装及定期发送的应用层心跳。
+// 可用于机器人、压测和集成测试。
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"happyAssistant/pkg/wshub"
+)
+
+var (
+	// ErrNotConnected 当前没有可用连接，通常处于重连过程中
+	ErrNotConnected = errors.New("wsclient: not connected")
+	// ErrClosed 客户端已关闭
+	ErrClosed = errors.New("wsclient: client is closed")
+)
+
+// Config 客户端配置
+type Config struct {
+	dialer       *websocket.Dialer
+	header       http.Header
+	readTimeout  time.Duration // 超过该时间未收到任何数据（包括心跳响应和服务端 Ping）视为连接断开
+	writeTimeout time.Duration
+	heartbeat    time.Duration // ProtocolClient 发送 HEARTBEAT_REQ 的间隔，<= 0 时不发送
+	reconnect    bool
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	maxRetries   int // 单次断线最多重连次数，<= 0 表示不限制
+	resume       bool
+	onMessage    func(messageType int, data []byte)
+	onConnect    func(resumed bool)
+	onDisconnect func(err error)
+}
+
+type Option func(*Config)
+
+// WithDialer 设置底层拨号器
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(cfg *Config) {
+		cfg.dialer = dialer
+	}
+}
+
+// WithHeader 设置握手请求头
+func WithHeader(header http.Header) Option {
+	return func(cfg *Config) {
+		cfg.header = header.Clone()
+	}
+}
+
+// WithReadTimeout 设置读超时，应大于心跳间隔（服务端开启 Ping 时还应大于 Ping 周期）
+// 只使用 Client 时不会发送心跳，服务端未开启 Ping 时需要自行定期发送消息，否则空闲连接会被服务端读超时断开
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout 设置写超时
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.writeTimeout = timeout
+	}
+}
+
+// WithReconnect 设置断线重连的退避区间，每次失败后等待时间翻倍，直到 max
+func WithReconnect(min, max time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.reconnect = true
+		cfg.minBackoff = min
+		cfg.maxBackoff = max
+	}
+}
+
+// WithoutReconnect 关闭断线重连
+func WithoutReconnect() Option {
+	return func(cfg *Config) {
+		cfg.reconnect = false
+	}
+}
+
+// WithMaxRetries 设置单次断线最多重连次数，<= 0 表示不限制
+func WithMaxRetries(retries int) Option {
+	return func(cfg *Config) {
+		cfg.maxRetries = retries
+	}
+}
+
+// WithResume 设置重连时是否携带恢复令牌恢复会话，默认开启
+func WithResume(resume bool) Option {
+	return func(cfg *Config) {
+		cfg.resume = resume
+	}
+}
+
+// WithMessageHandler 设置收到数据帧时的回调，在读协程中调用
+func WithMessageHandler(handler func(messageType int, data []byte)) Option {
+	return func(cfg *Config) {
+		cfg.onMessage = handler
+	}
+}
+
+// WithConnectHandler 设置连接建立（包括重连）后的回调，resumed 表示服务端恢复了之前的会话
+func WithConnectHandler(handler func(resumed bool)) Option {
+	return func(cfg *Config) {
+		cfg.onConnect = handler
+	}
+}
+
+// WithDisconnectHandler 设置连接断开或重连失败时的回调
+func WithDisconnectHandler(handler func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onDisconnect = handler
+	}
+}
+
+// Client WebSocket 客户端
+type Client struct {
+	url         string
+	conn        *websocket.Conn
+	connMu      sync.RWMutex
+	writeMu     sync.Mutex // 同一时间只允许一个协程写入数据帧
+	authToken   string
+	resumeToken string
+	lastSeq     atomic.Uint64 // 当前会话已收到的数据帧数量
+	done        chan struct{}
+	closeOnce   sync.Once
+	*Config
+}
+
+// Dial 连接服务端，首次连接失败时直接返回错误，之后的断线按配置自动重连
+func Dial(ctx context.Context, rawURL string, opts ...Option) (*Client, error) {
+	cfg := &Config{
+		dialer:       websocket.DefaultDialer,
+		header:       http.Header{},
+		readTimeout:  60 * time.Second,
+		writeTimeout: 10 * time.Second,
+		heartbeat:    15 * time.Second,
+		reconnect:    true,
+		minBackoff:   500 * time.Millisecond,
+		maxBackoff:   30 * time.Second,
+		resume:       true,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	c := &Client{
+		url:    rawURL,
+		done:   make(chan struct{}),
+		Config: cfg,
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go c.run(conn)
+	return c, nil
+}
+
+// SetAuthToken 设置会话令牌，之后的重连通过 Authorization 头携带，服务端据此恢复登录状态
+func (c *Client) SetAuthToken(token string) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	c.authToken = token
+}
+
+// Connected 当前是否已连接
+func (c *Client) Connected() bool {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.conn != nil
+}
+
+// Done 客户端关闭或放弃重连后关闭的通道
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// SendBinary 发送二进制帧
+func (c *Client) SendBinary(data []byte) error {
+	return c.Send(wshub.NewBinaryMessage(data))
+}
+
+// SendText 发送文本帧
+func (c *Client) SendText(data []byte) error {
+	return c.Send(wshub.NewTextMessage(data))
+}
+
+// Send 发送消息，未连接时返回 ErrNotConnected
+func (c *Client) Send(msg *wshub.Message) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	c.connMu.RLock()
+	conn := c.conn
+	c.connMu.RUnlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return fmt.Errorf("wsclient: set write deadline: %w", err)
+	}
+	if err := conn.WriteMessage(msg.Type, msg.Data); err != nil {
+		return fmt.Errorf("wsclient: write message: %w", err)
+	}
+	return nil
+}
+
+// Close 以正常关闭码断开连接并停止重连，服务端不会保留会话
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.connMu.RLock()
+		conn := c.conn
+		c.connMu.RUnlock()
+		if conn == nil {
+			return
+		}
+		data := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		_ = conn.WriteControl(websocket.CloseMessage, data, time.Now().Add(c.writeTimeout))
+		err = conn.Close()
+	})
+	return err
+}
+
+// dial 建立连接，开启会话恢复时携带恢复令牌和已收到的数据帧数量
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	target, err := url.Parse(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("wsclient: parse url: %w", err)
+	}
+	header := c.header.Clone()
+
+	c.connMu.RLock()
+	authToken, resumeToken := c.authToken, c.resumeToken
+	c.connMu.RUnlock()
+	if authToken != "" {
+		header.Set("Authorization", "Bearer "+authToken)
+	}
+	if c.resume && resumeToken != "" {
+		query := target.Query()
+		query.Set(wshub.ResumeTokenParam, resumeToken)
+		query.Set(wshub.LastSeqParam, strconv.FormatUint(c.lastSeq.Load(), 10))
+		target.RawQuery = query.Encode()
+	}
+
+	conn, resp, err := c.dialer.DialContext(ctx, target.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("wsclient: dial: %w (status %d)", err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("wsclient: dial: %w", err)
+	}
+
+	resumed := resp.Header.Get(wshub.ResumeStatusHeader) == "resumed"
+	if !resumed {
+		// 新会话从头计数
+		c.lastSeq.Store(0)
+	}
+	conn.SetPingHandler(func(appData string) error {
+		// 服务端 Ping 说明连接仍然可用，顺延读超时并回复 Pong
+		if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return err
+		}
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(c.writeTimeout))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	c.connMu.Lock()
+	c.conn = conn
+	c.resumeToken = resp.Header.Get(wshub.ResumeTokenHeader)
+	c.connMu.Unlock()
+
+	if c.onConnect != nil {
+		c.onConnect(resumed)
+	}
+	return conn, nil
+}
+
+// run 读取消息，断线后按配置重连，直到客户端关闭或放弃重连
+func (c *Client) run(conn *websocket.Conn) {
+	defer c.closeOnce.Do(func() { close(c.done) })
+	for {
+		err := c.read(conn)
+		c.connMu.Lock()
+		c.conn = nil
+		c.connMu.Unlock()
+		_ = conn.Close()
+
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		if c.onDisconnect != nil {
+			c.onDisconnect(err)
+		}
+		if !c.reconnect {
+			return
+		}
+		if conn = c.redial(); conn == nil {
+			return
+		}
+		select {
+		case <-c.done:
+			// 重连期间客户端被关闭
+			_ = conn.Close()
+			return
+		default:
+		}
+	}
+}
+
+// read 读协程，返回导致断开的错误
+func (c *Client) read(conn *websocket.Conn) error {
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return err
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.lastSeq.Add(1)
+		if c.onMessage != nil {
+			c.onMessage(messageType, data)
+		}
+	}
+}
+
+// jitter 在 [backoff/2, backoff] 内随机取等待时间，避免服务重启后大量客户端同时重连
+func jitter(backoff time.Duration) time.Duration {
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// redial 按指数退避重连，客户端关闭或超过最大重连次数时返回 nil
+func (c *Client) redial() *websocket.Conn {
+	backoff := c.minBackoff
+	for attempt := 1; c.maxRetries <= 0 || attempt <= c.maxRetries; attempt++ {
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(jitter(backoff)):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.dialer.HandshakeTimeout+c.writeTimeout)
+		conn, err := c.dial(ctx)
+		cancel()
+		if err == nil {
+			return conn
+		}
+		if c.onDisconnect != nil {
+			c.onDisconnect(err)
+		}
+		backoff = min(backoff*2, c.maxBackoff)
+	}
+	return nil
+}
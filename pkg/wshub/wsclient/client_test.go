@@ -0,0 +1,192 @@
+package wsclient
+
+import (
+	"context"
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wshubtest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 记录客户端收到的数据帧和连接事件
+type recorder struct {
+	mu          sync.Mutex
+	messages    []string
+	connects    []bool // 每次连接的 resumed
+	disconnects int
+	received    chan struct{}
+	connected   chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{received: make(chan struct{}, 64), connected: make(chan struct{}, 64)}
+}
+
+func (r *recorder) options() []Option {
+	return []Option{
+		WithMessageHandler(func(_ int, data []byte) {
+			r.mu.Lock()
+			r.messages = append(r.messages, string(data))
+			r.mu.Unlock()
+			r.received <- struct{}{}
+		}),
+		WithConnectHandler(func(resumed bool) {
+			r.mu.Lock()
+			r.connects = append(r.connects, resumed)
+			r.mu.Unlock()
+			r.connected <- struct{}{}
+		}),
+		WithDisconnectHandler(func(error) {
+			r.mu.Lock()
+			r.disconnects++
+			r.mu.Unlock()
+		}),
+	}
+}
+
+// wait 等待通道收到 n 个事件
+func wait(t *testing.T, ch chan struct{}, n int, name string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s %d", name, i+1)
+		}
+	}
+}
+
+func TestReconnectResumesSession(t *testing.T) {
+	const readDeadline = 200 * time.Millisecond
+	tests := []struct {
+		name         string
+		resume       bool
+		wantMessages []string
+		wantResumed  bool
+		wantLastSeq  uint64
+	}{
+		// 已收到的 a、b 不重复补发，断开期间发送的 c 在恢复后补发
+		{name: "resume", resume: true, wantMessages: []string{"a", "b", "c"}, wantResumed: true, wantLastSeq: 3},
+		// 新会话的序号从 0 开始计数
+		{name: "new session", resume: false, wantMessages: []string{"a", "b"}, wantLastSeq: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, err := wshub.NewHub(wshub.WithSessionResume(time.Second, 16))
+			if err != nil {
+				t.Fatalf("new hub: %v", err)
+			}
+			// 客户端不发送任何消息，服务端读超时后断开连接，会话进入宽限期
+			hub.SetClientOptions(wshub.WithReadDeadline(readDeadline))
+			server := wshubtest.NewServer(hub)
+			defer server.Close()
+
+			rec := newRecorder()
+			opts := append(rec.options(), WithResume(tt.resume), WithReconnect(2*readDeadline, 2*readDeadline))
+			client, err := Dial(context.Background(), server.URL, opts...)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer client.Close()
+			wait(t, rec.connected, 1, "connect")
+			serverClient, err := server.WaitOpen(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, msg := range []string{"a", "b"} {
+				if err := serverClient.SendText([]byte(msg)); err != nil {
+					t.Fatalf("send: %v", err)
+				}
+			}
+			wait(t, rec.received, 2, "message")
+
+			event, err := server.WaitClose(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Cause.Source != wshub.CloseSourceTimeout {
+				t.Fatalf("close source = %v, want timeout", event.Cause.Source)
+			}
+			// 客户端至少等待退避区间的一半才重连，此时会话处于断开状态
+			hub.Broadcast(wshub.NewTextMessage([]byte("c")))
+
+			wait(t, rec.connected, 1, "reconnect")
+			wait(t, rec.received, len(tt.wantMessages)-2, "replay")
+			time.Sleep(50 * time.Millisecond)
+
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			if len(rec.messages) != len(tt.wantMessages) {
+				t.Fatalf("messages = %v, want %v", rec.messages, tt.wantMessages)
+			}
+			for i := range rec.messages {
+				if rec.messages[i] != tt.wantMessages[i] {
+					t.Errorf("messages = %v, want %v", rec.messages, tt.wantMessages)
+					break
+				}
+			}
+			if len(rec.connects) != 2 || rec.connects[0] || rec.connects[1] != tt.wantResumed {
+				t.Errorf("connects = %v, want [false %v]", rec.connects, tt.wantResumed)
+			}
+			if got := client.lastSeq.Load(); got != tt.wantLastSeq {
+				t.Errorf("lastSeq = %d, want %d", got, tt.wantLastSeq)
+			}
+		})
+	}
+}
+
+func TestReconnectGivesUpAfterMaxRetries(t *testing.T) {
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	server := wshubtest.NewServer(hub)
+
+	rec := newRecorder()
+	opts := append(rec.options(), WithReconnect(10*time.Millisecond, 20*time.Millisecond), WithMaxRetries(2))
+	client, err := Dial(context.Background(), server.URL, opts...)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	serverClient, err := server.WaitOpen(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务端停止监听后断开连接，之后的重连全部失败
+	server.Close()
+	serverClient.Close()
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not give up reconnecting")
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	// 一次断开加两次重连失败
+	if rec.disconnects != 3 {
+		t.Errorf("disconnects = %d, want 3", rec.disconnects)
+	}
+	if err := client.SendText([]byte("x")); err != ErrClosed {
+		t.Errorf("send after give up = %v, want ErrClosed", err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, backoff := range []time.Duration{time.Millisecond, 500 * time.Millisecond, 30 * time.Second} {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 100; i++ {
+			wait := jitter(backoff)
+			if wait < backoff/2 || wait > backoff {
+				t.Fatalf("jitter(%v) = %v, want within [%v, %v]", backoff, wait, backoff/2, backoff)
+			}
+			seen[wait] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("jitter(%v) returned the same wait %d times", backoff, 100)
+		}
+	}
+}
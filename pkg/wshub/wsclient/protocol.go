@@ -0,0 +1,224 @@
+package wsclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+
+	"happyAssistant/internal/model"
+)
+
+// cancelRequestTimeout 发送取消请求的超时时间
+const cancelRequestTimeout = 5 * time.Second
+
+// ResponseError 服务端返回的失败响应
+type ResponseError struct {
+	Type    model.ProtocolType
+	Result  model.RESP_CODE
+	Code    model.ErrorCode
+	Msg     string
+	Details map[string]string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("wsclient: %s failed with %s (%s): %s", e.Type, e.Result, e.Code, e.Msg)
+}
+
+// WithHeartbeat 设置 ProtocolClient 发送 HEARTBEAT_REQ 的间隔，应与服务端 server.heartbeat.interval 一致，
+// 默认 15s，<= 0 时不发送；服务端默认不发送 Ping，不发送心跳的空闲连接会被服务端读超时断开
+func WithHeartbeat(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.heartbeat = interval
+	}
+}
+
+// ProtocolClient 基于 BaseRequest / BaseResponse 协议的客户端
+// 每个请求分配同一客户端内唯一的请求ID，服务端在响应中原样返回，据此将响应交给对应的请求，
+// 同一协议类型的多个请求可以同时等待。标记为 unsolicited 的服务端推送及未被请求认领的响应交给 onPush
+type ProtocolClient struct {
+	*Client
+	onPush  func(resp *model.BaseResponse)
+	nextID  atomic.Uint64
+	mu      sync.Mutex
+	waiters map[uint64]chan *model.BaseResponse
+}
+
+// DialProtocol 连接服务端并返回协议客户端，onPush 接收服务端推送及未被请求认领的响应，可以为 nil
+func DialProtocol(ctx context.Context, rawURL string, onPush func(resp *model.BaseResponse), opts ...Option) (*ProtocolClient, error) {
+	pc := &ProtocolClient{
+		onPush:  onPush,
+		waiters: make(map[uint64]chan *model.BaseResponse),
+	}
+	opts = append(opts, WithMessageHandler(pc.handleMessage))
+	client, err := Dial(ctx, rawURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	pc.Client = client
+	if pc.heartbeat > 0 {
+		go pc.heartbeatLoop()
+	}
+	return pc, nil
+}
+
+// Do 发送基础请求并等待匹配的响应，ctx 结束时返回其错误，并通知服务端取消该请求
+// req.RequestId 为 0 时自动分配；自行指定时调用方需保证同一客户端内不重复
+// 未开启会话恢复时，断线期间的响应会丢失，调用方应为 ctx 设置超时
+func (pc *ProtocolClient) Do(ctx context.Context, req *model.BaseRequest) (*model.BaseResponse, error) {
+	if req.RequestId == 0 {
+		req.RequestId = pc.nextID.Add(1)
+	}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("wsclient: marshal base request: %w", err)
+	}
+
+	ch := make(chan *model.BaseResponse, 1)
+	pc.mu.Lock()
+	pc.waiters[req.RequestId] = ch
+	pc.mu.Unlock()
+
+	if err := pc.SendBinary(data); err != nil {
+		pc.removeWaiter(req.RequestId)
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		pc.removeWaiter(req.RequestId)
+		// 心跳不需要取消
+		if req.Type != model.ProtocolType_CANCEL_REQ && req.Type != model.ProtocolType_HEARTBEAT_REQ {
+			go pc.cancelRequest(req.RequestId)
+		}
+		return nil, ctx.Err()
+	case <-pc.Done():
+		pc.removeWaiter(req.RequestId)
+		return nil, ErrClosed
+	}
+}
+
+// Call 发送请求并将成功响应的数据解析到 resp，失败响应返回 *ResponseError，resp 为 nil 时忽略响应数据
+func (pc *ProtocolClient) Call(ctx context.Context, protocolType model.ProtocolType, req, resp proto.Message) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("wsclient: marshal %s: %w", protocolType, err)
+	}
+	baseResp, err := pc.Do(ctx, &model.BaseRequest{Type: protocolType, Data: data})
+	if err != nil {
+		return err
+	}
+	if baseResp.Result != model.RESP_CODE_SUCCESS {
+		return &ResponseError{
+			Type:    baseResp.Type,
+			Result:  baseResp.Result,
+			Code:    baseResp.Code,
+			Msg:     baseResp.Msg,
+			Details: baseResp.Details,
+		}
+	}
+	if resp == nil {
+		return nil
+	}
+	if err := proto.Unmarshal(baseResp.Data, resp); err != nil {
+		return fmt.Errorf("wsclient: unmarshal %s: %w", baseResp.Type, err)
+	}
+	return nil
+}
+
+// Login 发送登录请求，成功后记录会话令牌，之后的重连无需重新登录
+func (pc *ProtocolClient) Login(ctx context.Context, req *model.LoginRequest) (*model.LoginResponse, error) {
+	resp := &model.LoginResponse{}
+	if err := pc.Call(ctx, model.ProtocolType_LOGIN_REQ, req, resp); err != nil {
+		return nil, err
+	}
+	if resp.SessionToken != "" {
+		pc.SetAuthToken(resp.SessionToken)
+	}
+	return resp, nil
+}
+
+// AckPush 确认已收到的服务端推送（类型为 PUSH 的推送中 data 解析出的 PushMessage.Id）
+func (pc *ProtocolClient) AckPush(ctx context.Context, ids ...string) error {
+	return pc.Call(ctx, model.ProtocolType_PUSH_ACK_REQ, &model.PushAckRequest{Ids: ids}, nil)
+}
+
+// Heartbeat 发送应用层心跳，返回服务端时间（毫秒时间戳）和计数
+func (pc *ProtocolClient) Heartbeat(ctx context.Context) (*model.HeartbeatResponse, error) {
+	resp := &model.HeartbeatResponse{}
+	if err := pc.Call(ctx, model.ProtocolType_HEARTBEAT_REQ, &model.HeartbeatRequest{ClientTime: time.Now().UnixMilli()}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// heartbeatLoop 按间隔发送心跳，保持空闲连接不被服务端读超时断开，重连期间跳过
+func (pc *ProtocolClient) heartbeatLoop() {
+	ticker := time.NewTicker(pc.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pc.Done():
+			return
+		case <-ticker.C:
+			if !pc.Connected() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), pc.heartbeat)
+			_, _ = pc.Heartbeat(ctx)
+			cancel()
+		}
+	}
+}
+
+// cancelRequest 通知服务端取消处理中的请求，不等待结果
+func (pc *ProtocolClient) cancelRequest(requestID uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelRequestTimeout)
+	defer cancel()
+	_ = pc.Call(ctx, model.ProtocolType_CANCEL_REQ, &model.CancelRequest{RequestId: requestID}, nil)
+}
+
+// handleMessage 解析服务端响应并交给等待中的请求
+func (pc *ProtocolClient) handleMessage(messageType int, data []byte) {
+	if messageType != websocket.BinaryMessage {
+		return
+	}
+	resp := &model.BaseResponse{}
+	if err := proto.Unmarshal(data, resp); err != nil {
+		return
+	}
+
+	if !resp.Unsolicited && resp.RequestId != 0 {
+		if ch := pc.takeWaiter(resp.RequestId); ch != nil {
+			ch <- resp
+			return
+		}
+	}
+	if pc.onPush != nil {
+		pc.onPush(resp)
+	}
+}
+
+// takeWaiter 取出等待该请求ID的请求
+func (pc *ProtocolClient) takeWaiter(requestID uint64) chan *model.BaseResponse {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	ch, ok := pc.waiters[requestID]
+	if ok {
+		delete(pc.waiters, requestID)
+	}
+	return ch
+}
+
+// removeWaiter 移除已放弃等待的请求
+func (pc *ProtocolClient) removeWaiter(requestID uint64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	delete(pc.waiters, requestID)
+}
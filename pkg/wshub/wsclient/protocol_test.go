@@ -0,0 +1,229 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"happyAssistant/internal/model"
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wshubtest"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// newProtocolServer 启动按 BaseRequest 协议收发的测试服务端，handle 在读协程中处理每个请求
+func newProtocolServer(t *testing.T, handle func(client wshub.IClient, req *model.BaseRequest), opts ...wshub.ClientOption) *wshubtest.Server {
+	t.Helper()
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	hub.SetClientOptions(opts...)
+	hub.OnMessage = func(client wshub.IClient, msg []byte) {
+		req := &model.BaseRequest{}
+		if err := proto.Unmarshal(msg, req); err != nil {
+			t.Errorf("unmarshal request: %v", err)
+			return
+		}
+		handle(client, req)
+	}
+	server := wshubtest.NewServer(hub)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// reply 向客户端发送响应，data 为 nil 时不携带数据
+func reply(t *testing.T, client wshub.IClient, resp *model.BaseResponse, data proto.Message) {
+	t.Helper()
+	if data != nil {
+		var err error
+		if resp.Data, err = proto.Marshal(data); err != nil {
+			t.Fatalf("marshal response data: %v", err)
+		}
+	}
+	frame, err := proto.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	if err := client.SendBinary(frame); err != nil {
+		t.Errorf("send response: %v", err)
+	}
+}
+
+func TestDoMatchesResponseByRequestID(t *testing.T) {
+	const calls = 3
+	var mu sync.Mutex
+	var received []*model.BaseRequest
+	server := newProtocolServer(t, func(client wshub.IClient, req *model.BaseRequest) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, req)
+		if len(received) < calls {
+			return
+		}
+		// 推送不属于任何请求，即使请求ID相同也交给 onPush
+		reply(t, client, &model.BaseResponse{Type: model.ProtocolType_PUSH, RequestId: received[0].RequestId, Unsolicited: true}, nil)
+		// 收齐后倒序回复，响应按请求ID而不是到达顺序交给请求
+		for i := len(received) - 1; i >= 0; i-- {
+			heartbeat := &model.HeartbeatRequest{}
+			if err := proto.Unmarshal(received[i].Data, heartbeat); err != nil {
+				t.Errorf("unmarshal heartbeat: %v", err)
+			}
+			reply(t, client, &model.BaseResponse{
+				Type:      model.ProtocolType_HEARTBEAT_RESP,
+				Result:    model.RESP_CODE_SUCCESS,
+				RequestId: received[i].RequestId,
+			}, &model.HeartbeatResponse{ClientTime: heartbeat.ClientTime})
+		}
+	})
+
+	pushes := make(chan *model.BaseResponse, 1)
+	client, err := DialProtocol(context.Background(), server.URL, func(resp *model.BaseResponse) { pushes <- resp },
+		WithoutReconnect(), WithHeartbeat(0))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 1; i <= calls; i++ {
+		wg.Add(1)
+		go func(clientTime int64) {
+			defer wg.Done()
+			resp := &model.HeartbeatResponse{}
+			if err := client.Call(ctx, model.ProtocolType_HEARTBEAT_REQ, &model.HeartbeatRequest{ClientTime: clientTime}, resp); err != nil {
+				t.Errorf("call %d: %v", clientTime, err)
+				return
+			}
+			if resp.ClientTime != clientTime {
+				t.Errorf("call %d got response for %d", clientTime, resp.ClientTime)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	select {
+	case push := <-pushes:
+		if push.Type != model.ProtocolType_PUSH {
+			t.Errorf("push type = %s, want PUSH", push.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push not delivered to onPush")
+	}
+}
+
+func TestLoginSetsAuthToken(t *testing.T) {
+	const readDeadline = 200 * time.Millisecond
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	// 客户端不发送心跳，服务端读超时断开后客户端自动重连
+	hub.SetClientOptions(wshub.WithReadDeadline(readDeadline))
+	authorizations := make(chan string, 4)
+	hub.SetAuthenticator(func(r *http.Request) (map[string]interface{}, error) {
+		authorizations <- r.Header.Get("Authorization")
+		return nil, nil
+	})
+	hub.OnMessage = func(client wshub.IClient, msg []byte) {
+		req := &model.BaseRequest{}
+		if err := proto.Unmarshal(msg, req); err != nil {
+			t.Errorf("unmarshal request: %v", err)
+			return
+		}
+		reply(t, client, &model.BaseResponse{
+			Type:      model.ProtocolType_LOGIN_RESP,
+			Result:    model.RESP_CODE_SUCCESS,
+			RequestId: req.RequestId,
+		}, &model.LoginResponse{SessionToken: "session-token"})
+	}
+	server := wshubtest.NewServer(hub)
+	defer server.Close()
+
+	client, err := DialProtocol(context.Background(), server.URL, nil,
+		WithReconnect(10*time.Millisecond, 20*time.Millisecond), WithHeartbeat(0))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := client.Login(ctx, &model.LoginRequest{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.SessionToken != "session-token" {
+		t.Errorf("session token = %q, want session-token", resp.SessionToken)
+	}
+
+	want := []string{"", "Bearer session-token"}
+	for i, w := range want {
+		select {
+		case got := <-authorizations:
+			if got != w {
+				t.Errorf("handshake %d Authorization = %q, want %q", i+1, got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("handshake %d not received", i+1)
+		}
+	}
+}
+
+func TestAckPush(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   model.RESP_CODE
+		code     model.ErrorCode
+		wantCode model.ErrorCode // 期望 ResponseError 的错误码，NO_ERROR 表示成功
+	}{
+		{name: "success", result: model.RESP_CODE_SUCCESS},
+		{name: "failure", result: model.RESP_CODE_ERROR, code: model.ErrorCode_NOT_LOGGED_IN, wantCode: model.ErrorCode_NOT_LOGGED_IN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acks := make(chan *model.PushAckRequest, 1)
+			server := newProtocolServer(t, func(client wshub.IClient, req *model.BaseRequest) {
+				ack := &model.PushAckRequest{}
+				if err := proto.Unmarshal(req.Data, ack); err != nil {
+					t.Errorf("unmarshal ack: %v", err)
+				}
+				if req.Type != model.ProtocolType_PUSH_ACK_REQ {
+					t.Errorf("request type = %s, want PUSH_ACK_REQ", req.Type)
+				}
+				acks <- ack
+				reply(t, client, &model.BaseResponse{
+					Type:      model.ProtocolType_PUSH_ACK_RESP,
+					Result:    tt.result,
+					Code:      tt.code,
+					RequestId: req.RequestId,
+				}, nil)
+			})
+			client, err := DialProtocol(context.Background(), server.URL, nil, WithoutReconnect(), WithHeartbeat(0))
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err = client.AckPush(ctx, "p1", "p2")
+			var respErr *ResponseError
+			switch {
+			case tt.wantCode == model.ErrorCode_NO_ERROR && err != nil:
+				t.Errorf("AckPush: %v", err)
+			case tt.wantCode != model.ErrorCode_NO_ERROR && (!errors.As(err, &respErr) || respErr.Code != tt.wantCode):
+				t.Errorf("AckPush error = %v, want code %s", err, tt.wantCode)
+			}
+			ack := <-acks
+			if len(ack.Ids) != 2 || ack.Ids[0] != "p1" || ack.Ids[1] != "p2" {
+				t.Errorf("acked ids = %v, want [p1 p2]", ack.Ids)
+			}
+		})
+	}
+}
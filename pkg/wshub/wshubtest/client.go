@@ -0,0 +1,202 @@
+// Package wshubtest 提供测试 wshub 业务代码的工具：
+// 记录发送内容的内存客户端 Client，可直接传给 OnMessage 等业务回调；
+// 以及基于 net/http/httptest 的 Server，用真实连接驱动 WebSocketHub 的回调。
+package wshubtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"happyAssistant/pkg/wshub"
+)
+
+// Client 内存中的 IClient 实现，记录所有发送的数据帧，不建立任何连接
+// GetBaseClient 返回 nil，不能用于 Hub 的主题（Join / Publish）等需要底层连接的操作，
+// 这类测试请通过 Server 建立真实连接
+type Client struct {
+	mu      sync.Mutex
+	frames  []*wshub.Message
+	closed  bool
+	cause   wshub.CloseCause
+	ctx     context.Context
+	notify  chan struct{} // 每次发送或关闭后关闭并替换，用于等待新帧
+	SendErr error         // 不为 nil 时发送方法直接返回该错误，用于模拟发送失败
+}
+
+// 确保Client实现IClient接口，并保留消息的优先级
+var (
+	_ wshub.IClient        = (*Client)(nil)
+	_ wshub.PrioritySender = (*Client)(nil)
+)
+
+// NewClient 创建内存客户端
+func NewClient() *Client {
+	return &Client{
+		ctx:    context.Background(),
+		notify: make(chan struct{}),
+	}
+}
+
+// SendText 记录文本帧
+func (c *Client) SendText(msg []byte) error {
+	return c.record(wshub.NewTextMessage(msg))
+}
+
+// SendBinary 记录二进制帧
+func (c *Client) SendBinary(msg []byte) error {
+	return c.record(wshub.NewBinaryMessage(msg))
+}
+
+// Send 记录消息，保留其优先级
+func (c *Client) Send(msg *wshub.Message) error {
+	return c.record(msg)
+}
+
+func (c *Client) record(msg *wshub.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.SendErr != nil {
+		return c.SendErr
+	}
+	if c.closed {
+		return fmt.Errorf("client is closed")
+	}
+	// 复制数据，避免调用方复用缓冲区影响记录
+	c.frames = append(c.frames, &wshub.Message{Type: msg.Type, Data: append([]byte(nil), msg.Data...), Priority: msg.Priority})
+	c.signalLocked()
+	return nil
+}
+
+// Close 标记客户端已关闭，之后的发送返回错误
+func (c *Client) Close() {
+	c.CloseWithReason(websocket.CloseNormalClosure, "")
+}
+
+// CloseWithReason 标记客户端已关闭并记录关闭码和原因，只记录第一次关闭
+func (c *Client) CloseWithReason(code int, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		c.cause = wshub.CloseCause{Source: wshub.CloseSourceServer, Code: code, Text: text}
+		c.signalLocked()
+	}
+}
+
+// signalLocked 唤醒等待中的协程，调用方需持有 mu
+func (c *Client) signalLocked() {
+	close(c.notify)
+	c.notify = make(chan struct{})
+}
+
+// GetBaseClient 内存客户端没有底层连接，返回 nil；传给 Hub.Join 等操作会直接 panic
+func (c *Client) GetBaseClient() *wshub.Client {
+	return nil
+}
+
+// Closed 是否已被关闭
+func (c *Client) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// CloseCause 获取关闭原因，未关闭时返回零值
+func (c *Client) CloseCause() wshub.CloseCause {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cause
+}
+
+// Frames 获取已发送的所有数据帧
+func (c *Client) Frames() []*wshub.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*wshub.Message(nil), c.frames...)
+}
+
+// BinaryFrames 获取已发送的所有二进制帧内容
+func (c *Client) BinaryFrames() [][]byte {
+	var frames [][]byte
+	for _, frame := range c.Frames() {
+		if frame.Type == websocket.BinaryMessage {
+			frames = append(frames, frame.Data)
+		}
+	}
+	return frames
+}
+
+// LastFrame 获取最后发送的数据帧，没有时返回 nil
+func (c *Client) LastFrame() *wshub.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.frames) == 0 {
+		return nil
+	}
+	return c.frames[len(c.frames)-1]
+}
+
+// Reset 清空已记录的数据帧
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames = nil
+}
+
+// WaitFrames 等待至少记录 n 个数据帧，用于消息在分发器中异步处理的场景，超时返回错误
+func (c *Client) WaitFrames(n int, timeout time.Duration) ([]*wshub.Message, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		c.mu.Lock()
+		if len(c.frames) >= n {
+			frames := append([]*wshub.Message(nil), c.frames...)
+			c.mu.Unlock()
+			return frames, nil
+		}
+		notify, count := c.notify, len(c.frames)
+		c.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-deadline.C:
+			return nil, fmt.Errorf("wshubtest: got %d frames, want %d after %s", count, n, timeout)
+		}
+	}
+}
+
+// SetContextValue 设置上下文值
+func (c *Client) SetContextValue(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = context.WithValue(c.ctx, key, value)
+}
+
+// GetContextValue 获取上下文值
+func (c *Client) GetContextValue(key string) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ctx.Value(key)
+}
+
+// GetContextString 获取字符串类型的上下文值
+func (c *Client) GetContextString(key string) string {
+	value, _ := c.GetContextValue(key).(string)
+	return value
+}
+
+// GetContextInt 获取整数类型的上下文值
+func (c *Client) GetContextInt(key string) int {
+	value, _ := c.GetContextValue(key).(int)
+	return value
+}
+
+// GetContextBool 获取布尔类型的上下文值
+func (c *Client) GetContextBool(key string) bool {
+	value, _ := c.GetContextValue(key).(bool)
+	return value
+}
@@ -0,0 +1,100 @@
+package wshubtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"happyAssistant/pkg/wshub"
+)
+
+// eventBuffer 连接事件通道容量，超出后丢弃最新事件
+const eventBuffer = 1024
+
+// Server 基于 httptest.Server 的测试服务端
+// 在 Hub 已有的 OnOpen / OnClose 回调之外记录连接事件，便于测试等待服务端完成处理
+type Server struct {
+	Hub    *wshub.WebSocketHub
+	URL    string // WebSocket 地址，形如 ws://127.0.0.1:port
+	server *httptest.Server
+	opened chan wshub.IClient
+	closed chan CloseEvent
+}
+
+// CloseEvent 连接关闭事件
+type CloseEvent struct {
+	Client wshub.IClient
+	Cause  wshub.CloseCause
+}
+
+// NewServer 启动测试服务端，需在设置完 Hub 的回调之后调用；测试结束后调用 Close
+func NewServer(hub *wshub.WebSocketHub) *Server {
+	s := &Server{
+		Hub:    hub,
+		opened: make(chan wshub.IClient, eventBuffer),
+		closed: make(chan CloseEvent, eventBuffer),
+	}
+
+	onOpen, onClose := hub.OnOpen, hub.OnClose
+	hub.OnOpen = func(client wshub.IClient) {
+		if onOpen != nil {
+			onOpen(client)
+		}
+		select {
+		case s.opened <- client:
+		default:
+		}
+	}
+	hub.OnClose = func(client wshub.IClient, cause wshub.CloseCause) {
+		if onClose != nil {
+			onClose(client, cause)
+		}
+		select {
+		case s.closed <- CloseEvent{Client: client, Cause: cause}:
+		default:
+		}
+	}
+
+	s.server = httptest.NewServer(hub)
+	s.URL = "ws" + strings.TrimPrefix(s.server.URL, "http")
+	return s
+}
+
+// Dial 建立 WebSocket 连接，query 为附加的查询参数（不含 ?），例如 token=xxx
+func (s *Server) Dial(query string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	target := s.URL
+	if query != "" {
+		target += "?" + query
+	}
+	return websocket.DefaultDialer.Dial(target, header)
+}
+
+// WaitOpen 等待下一个完成 OnOpen 的客户端
+func (s *Server) WaitOpen(timeout time.Duration) (wshub.IClient, error) {
+	return waitEvent(s.opened, "open", timeout)
+}
+
+// WaitClose 等待下一个完成 OnClose 的客户端及其关闭原因
+func (s *Server) WaitClose(timeout time.Duration) (CloseEvent, error) {
+	return waitEvent(s.closed, "close", timeout)
+}
+
+func waitEvent[T any](events chan T, name string, timeout time.Duration) (T, error) {
+	select {
+	case event := <-events:
+		return event, nil
+	case <-time.After(timeout):
+		var zero T
+		return zero, fmt.Errorf("wshubtest: no %s event after %s", name, timeout)
+	}
+}
+
+// Close 关闭测试服务端
+// 已升级的连接不受 httptest.Server 管理，需要时先调用 Hub.Shutdown
+func (s *Server) Close() {
+	s.server.Close()
+}
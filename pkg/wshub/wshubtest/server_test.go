@@ -0,0 +1,85 @@
+package wshubtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"happyAssistant/pkg/wshub"
+	"happyAssistant/pkg/wshub/wshubtest"
+)
+
+func TestServerEndToEnd(t *testing.T) {
+	hub, err := wshub.NewHub()
+	if err != nil {
+		t.Fatalf("new hub: %v", err)
+	}
+	hub.OnOpen = func(client wshub.IClient) {
+		hub.Join(client, "lab:1")
+	}
+	hub.OnMessage = func(client wshub.IClient, msg []byte) {
+		_ = client.SendText(append([]byte("echo:"), msg...))
+	}
+	server := wshubtest.NewServer(hub)
+	defer server.Close()
+
+	conn, _, err := server.Dial("", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client, err := server.WaitOpen(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetBaseClient() == nil {
+		t.Fatal("server client has no base client")
+	}
+	if got := hub.ClientCount(); got != 1 {
+		t.Errorf("ClientCount() = %d, want 1", got)
+	}
+
+	read := func(want string) {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("received %q, want %q", data, want)
+		}
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	read("echo:hello")
+
+	if sent := hub.Publish("lab:1", wshub.NewTextMessage([]byte("lab update"))); sent != 1 {
+		t.Errorf("Publish() = %d, want 1", sent)
+	}
+	read("lab update")
+	if sent := hub.Publish("lab:2", wshub.NewTextMessage([]byte("other lab"))); sent != 0 {
+		t.Errorf("Publish() to another topic = %d, want 0", sent)
+	}
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "bye")
+	if err := conn.WriteMessage(websocket.CloseMessage, closeMsg); err != nil {
+		t.Fatalf("write close: %v", err)
+	}
+	event, err := server.WaitClose(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Client != client || event.Cause.Source != wshub.CloseSourcePeer || event.Cause.Code != websocket.CloseGoingAway {
+		t.Errorf("close event = %+v, want client close %d", event.Cause, websocket.CloseGoingAway)
+	}
+	if got := hub.ClientCount(); got != 0 {
+		t.Errorf("ClientCount() after close = %d, want 0", got)
+	}
+	if members := hub.RoomMembers("lab:1"); len(members) != 0 {
+		t.Errorf("room still has %d members after close", len(members))
+	}
+}